@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/casbin/casbin/v2"
@@ -9,15 +12,21 @@ import (
 	"github.com/unanet/eve/internal/config"
 	"github.com/unanet/eve/pkg/s3"
 	"github.com/unanet/eve/pkg/scm"
+	"github.com/unanet/eve/pkg/storage"
 	"go.uber.org/zap"
 
 	"github.com/unanet/eve/internal/api"
 	"github.com/unanet/eve/internal/data"
 	"github.com/unanet/eve/internal/service/crud"
 	"github.com/unanet/eve/internal/service/plans"
+	"github.com/unanet/eve/internal/service/progress"
 	"github.com/unanet/eve/internal/service/releases"
+	"github.com/unanet/eve/internal/tracing"
 	"github.com/unanet/eve/pkg/artifactory"
+	"github.com/unanet/eve/pkg/envelope"
+	"github.com/unanet/eve/pkg/notify"
 	"github.com/unanet/eve/pkg/queue"
+	"github.com/unanet/eve/pkg/secrets"
 	"github.com/unanet/go/pkg/identity"
 	"github.com/unanet/go/pkg/log"
 )
@@ -48,34 +57,129 @@ func main() {
 		}
 	}
 
+	keyring, err := envelope.New(config.GetEncryptionConfig())
+	if err != nil {
+		log.Logger.Panic("Failed to load the metadata encryption keys", zap.Error(err))
+	}
+
+	if flags.RotateMetadataKeysFlag {
+		rotated, err := crud.NewManager(data.NewRepo(db), keyring).RotateMetadataEncryption(context.Background())
+		if err != nil {
+			log.Logger.Panic("Failed to rotate the metadata encryption", zap.Int("rotated", rotated), zap.Error(err))
+		}
+		log.Logger.Info("Rotated the metadata encryption", zap.Int("rotated", rotated))
+	}
+
 	if !flags.ServerFlag {
 		return
 	}
 
 	cfg := config.GetConfig()
 
-	awsSession, err := session.NewSession(&aws.Config{
-		Region: aws.String(cfg.AWSRegion),
-	},
-	)
+	shutdownTracing, err := tracing.Start(context.Background(), tracing.Config{
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+		ServiceName: cfg.ServiceName,
+	})
 	if err != nil {
-		log.Logger.Panic("Failed to create AWS Session", zap.Error(err))
+		log.Logger.Panic("Failed to start tracing", zap.Error(err))
 	}
-	apiQueue := queue.NewQ(awsSession, queue.Config{
+
+	// AWS is only needed for the sqs queue and s3 plan storage, a configured region still lets s3 plans stored before
+	// the backend changed be read
+	var awsSession *session.Session
+	if cfg.QueueBackend == queue.BackendSQS || cfg.PlanStorage == storage.TypeS3 || len(cfg.AWSRegion) > 0 {
+		if len(cfg.AWSRegion) == 0 {
+			log.Logger.Panic("AWS Region is required for the sqs queue backend and s3 plan storage")
+		}
+		awsSession, err = session.NewSession(&aws.Config{
+			Region: aws.String(cfg.AWSRegion),
+		},
+		)
+		if err != nil {
+			log.Logger.Panic("Failed to create AWS Session", zap.Error(err))
+		}
+	}
+	apiQConfig := queue.Config{
 		MaxNumberOfMessage: cfg.ApiQMaxNumberOfMessage,
 		QueueURL:           cfg.ApiQUrl,
 		WaitTimeSecond:     cfg.ApiQWaitTimeSecond,
 		VisibilityTimeout:  cfg.ApiQVisibilityTimeout,
-	})
+	}
+
+	var apiQueue queue.Backend
+	switch cfg.QueueBackend {
+	case queue.BackendSQS:
+		apiQueue = queue.NewQ(awsSession, apiQConfig)
+	case queue.BackendPostgres:
+		apiQueue = queue.NewPostgresQ(db, apiQConfig)
+	default:
+		log.Logger.Panic("Invalid Queue Backend", zap.String("backend", cfg.QueueBackend))
+	}
+
+	// every downloader is registered so plans that were stored before the backend changed can still be read
+	planDownloaders := storage.Downloaders{
+		storage.TypeFilesystem: storage.NewFilesystem(storage.FilesystemConfig{Dir: cfg.PlanStorageDir}),
+		storage.TypePostgres:   storage.NewPostgres(db),
+	}
+	if awsSession != nil {
+		planDownloaders[storage.TypeS3] = s3.NewDownloader(awsSession)
+	}
+
+	var planUploader storage.Uploader
+	switch cfg.PlanStorage {
+	case storage.TypeS3:
+		if len(cfg.S3Bucket) == 0 {
+			log.Logger.Panic("S3 Bucket is required for s3 plan storage")
+		}
+		planUploader = s3.NewUploader(awsSession, s3.Config{Bucket: cfg.S3Bucket})
+	case storage.TypeFilesystem:
+		planUploader = storage.NewFilesystem(storage.FilesystemConfig{Dir: cfg.PlanStorageDir})
+	case storage.TypePostgres:
+		planUploader = storage.NewPostgres(db)
+	default:
+		log.Logger.Panic("Invalid Plan Storage", zap.String("storage", cfg.PlanStorage))
+	}
 
 	repo := data.NewRepo(db)
 	artifactoryClient := artifactory.NewClient(cfg.ArtifactoryConfig)
-	deploymentPlanGenerator := plans.NewPlanGenerator(repo, artifactoryClient, apiQueue)
-	crudManager := crud.NewManager(repo)
+	crudManager := crud.NewManager(repo, keyring)
+	deploymentPlanGenerator := plans.NewPlanGenerator(repo, artifactoryClient, apiQueue, crudManager)
 	scmClient := scm.New()
-	releaseSvc := releases.NewReleaseSvc(repo, artifactoryClient, scmClient)
+	releaseSvc := releases.NewReleaseSvc(repo, artifactoryClient, scmClient, crudManager)
 
-	controllers, err := api.InitializeControllers(deploymentPlanGenerator, crudManager, releaseSvc)
+	notifier, err := notify.New(cfg.NotifyConfig)
+	if err != nil {
+		log.Logger.Panic("Failed to create the deployment notifier", zap.Error(err))
+	}
+
+	progressBroker := progress.NewBroker(repo, dbConfig.DbConnectionString())
+	err = progressBroker.Start()
+	if err != nil {
+		log.Logger.Panic("Failed to start the deployment progress broker", zap.Error(err))
+	}
+
+	apiWorker := queue.NewWorker("eve-api", apiQueue, cfg.ApiQWorkerTimeout)
+	apiWorker.SetConcurrency(cfg.ApiQWorkerConcurrency, cfg.ApiQCommandConcurrency)
+	apiWorker.SetVisibilityHeartbeat(time.Duration(cfg.ApiQVisibilityTimeout) * time.Second)
+	deploymentQueue := plans.NewQueue(
+		apiWorker,
+		repo,
+		crudManager,
+		planUploader,
+		planDownloaders,
+		notifier,
+		progressBroker,
+		secrets.New(cfg.SecretsConfig),
+	)
+
+	apiWorker.SetRetryPolicy(queue.RetryPolicy{
+		MaxAttempts: cfg.ApiQMaxAttempts,
+		BaseDelay:   cfg.ApiQRetryBaseDelay,
+		MaxDelay:    cfg.ApiQRetryMaxDelay,
+	}, deploymentQueue)
+
+	controllers, err := api.InitializeControllers(deploymentPlanGenerator, deploymentQueue, crudManager, releaseSvc, progressBroker)
 	if err != nil {
 		log.Logger.Panic("Unable to Initialize the Controllers")
 	}
@@ -104,23 +208,40 @@ func main() {
 		log.Logger.Panic("Failed to Create Api App", zap.Error(err))
 	}
 
-	deploymentQueue := plans.NewQueue(
-		queue.NewWorker("eve-api", apiQueue, cfg.ApiQWorkerTimeout),
+	cron := plans.NewDeploymentCron(repo, deploymentPlanGenerator, crudManager, cfg.CronTimeout)
+	reaper := plans.NewDeploymentReaper(repo, deploymentQueue, cfg.DeploymentDeadline, cfg.CronTimeout)
+	callbackDispatcher := plans.NewCallbackDispatcher(
 		repo,
-		crudManager,
-		s3.NewUploader(awsSession, s3.Config{Bucket: cfg.S3Bucket}),
-		s3.NewDownloader(awsSession),
-		plans.NewCallback(cfg.HttpCallbackTimeout),
+		plans.NewCallback(cfg.HttpCallbackTimeout, plans.CallbackSecrets{
+			Default: cfg.HttpCallbackSecret,
+			Hosts:   cfg.HttpCallbackSecrets,
+		}),
+		queue.RetryPolicy{
+			MaxAttempts: cfg.HttpCallbackMaxAttempts,
+			BaseDelay:   cfg.HttpCallbackRetryBaseDelay,
+			MaxDelay:    cfg.HttpCallbackRetryMaxDelay,
+		},
+		cfg.HttpCallbackTimeout,
 	)
-
-	cron := plans.NewDeploymentCron(repo, deploymentPlanGenerator, cfg.CronTimeout)
 	if !cfg.LocalDev {
 		cron.Start()
+		reaper.Start()
+		callbackDispatcher.Start()
 		deploymentQueue.Start()
 	}
 
 	apiServer.Start(func() {
 		cron.Stop()
+		reaper.Stop()
+		callbackDispatcher.Stop()
 		deploymentQueue.Stop()
+		notifier.Wait()
+		progressBroker.Stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Logger.Error("Failed to flush traces", zap.Error(err))
+		}
 	})
 }
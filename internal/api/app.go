@@ -12,11 +12,14 @@ import (
 	"github.com/casbin/casbin/v2"
 	"github.com/golang-jwt/jwt"
 	"github.com/unanet/eve/internal/config"
+	"github.com/unanet/eve/internal/data"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/jwtauth"
 	"github.com/go-chi/render"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/unanet/go/pkg/errors"
@@ -138,6 +141,7 @@ func (a *Api) Start(onShutdown ...func()) {
 
 func (a *Api) setup() {
 	middleware.SetupMiddleware(a.r, 60*time.Second)
+	a.r.Use(tracingMiddleware)
 	// Basic CORS
 	// for more ideas, see: https://developer.github.com/v3/#cross-origin-resource-sharing
 	a.r.Use(cors.Handler(cors.Options{
@@ -159,6 +163,17 @@ func (a *Api) setup() {
 	}
 }
 
+// tracingMiddleware starts a span for every request, the span is named after the route once chi has matched it
+func tracingMiddleware(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && len(rctx.RoutePattern()) > 0 {
+			trace.SpanFromContext(r.Context()).SetName(fmt.Sprintf("%s %s", r.Method, rctx.RoutePattern()))
+		}
+	})
+	return otelhttp.NewHandler(named, "eve-api")
+}
+
 // TODO: Refactor into pkg
 // This code is duped in cloud-api
 func (a *Api) authenticationMiddleware() func(http.Handler) http.Handler {
@@ -167,6 +182,7 @@ func (a *Api) authenticationMiddleware() func(http.Handler) http.Handler {
 			ctx := r.Context()
 			// Admin token, you shall PASS!!!
 			if jwtauth.TokenFromHeader(r) == a.adminToken {
+				ctx = data.WithUser(ctx, "admin")
 				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
@@ -195,12 +211,23 @@ func (a *Api) authenticationMiddleware() func(http.Handler) http.Handler {
 				return
 			}
 
+			ctx = data.WithUser(ctx, extractUser(claims))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
 		return http.HandlerFunc(hfn)
 	}
 }
 
+// extractUser returns who the token was issued to, metadata and definition history is attributed to them
+func extractUser(claims jwt.MapClaims) string {
+	for _, claim := range []string{"preferred_username", "email", "sub"} {
+		if user, ok := claims[claim].(string); ok && len(user) > 0 {
+			return user
+		}
+	}
+	return "unknown"
+}
+
 // TODO: Refactor into pkg
 // This code is duped in cloud-api
 func extractRole(ctx context.Context, claims jwt.MapClaims) string {
@@ -3,13 +3,16 @@ package api
 import (
 	"github.com/unanet/eve/internal/service/crud"
 	"github.com/unanet/eve/internal/service/plans"
+	"github.com/unanet/eve/internal/service/progress"
 	"github.com/unanet/eve/internal/service/releases"
 )
 
 func InitializeControllers(
 	deploymentPlanGenerator *plans.PlanGenerator,
+	deploymentQueue *plans.Queue,
 	manager *crud.Manager,
 	releaseSvc *releases.ReleaseSvc,
+	broker *progress.Broker,
 ) ([]Controller, error) {
 	return []Controller{
 		NewPingController(),
@@ -17,15 +20,17 @@ func InitializeControllers(
 		NewClusterController(manager),
 		NewDefinitionsController(manager),
 		NewDeploymentPlansController(deploymentPlanGenerator),
-		NewDeploymentsController(manager),
+		NewDeploymentsController(manager, deploymentPlanGenerator, deploymentQueue, broker),
 		NewDeploymentsCronController(manager),
-		NewEnvironmentController(manager),
+		NewEnvironmentController(manager, deploymentPlanGenerator, broker),
 		NewReleaseController(releaseSvc),
 		NewFeedController(manager),
 		NewJobController(manager),
 		NewEnvironmentFeedMapController(manager),
 		NewMetadataController(manager),
-		NewNamespaceController(manager),
-		NewServiceController(manager),
+		NewNamespaceController(manager, deploymentPlanGenerator),
+		NewQueueController(manager, deploymentQueue),
+		NewServiceController(manager, deploymentPlanGenerator),
+		NewSubscriptionController(manager),
 	}, nil
 }
@@ -31,11 +31,16 @@ func (c DefinitionsController) Setup(r *Routers) {
 	// Sorted here to go above thee definitions by id
 	r.Auth.Get("/definitions/job-maps", c.definitionJobMaps)
 	r.Auth.Get("/definitions/service-maps", c.definitionServiceMaps)
+	r.Auth.Post("/definitions/service-maps/preview", c.previewDefinitionServiceMap)
+	r.Auth.Post("/definitions/job-maps/preview", c.previewDefinitionJobMap)
 
 	r.Auth.Delete("/definitions/{definition}/{key}", c.deleteDefinitionKey)
 	r.Auth.Delete("/definitions/{definition}", c.deleteDefinition)
 	r.Auth.Get("/definitions/{definition}", c.getDefinition)
 
+	r.Auth.Get("/definitions/{definition}/history", c.definitionRevisions)
+	r.Auth.Post("/definitions/{definition}/restore/{revision}", c.restoreDefinitionRevision)
+
 	r.Auth.Put("/definitions/{definition}/service-maps", c.upsertDefinitionServiceMap)
 	r.Auth.Delete("/definitions/{definition}/service-maps/{description}", c.deleteServiceDefinitionMap)
 	r.Auth.Get("/definitions/{definition}/service-maps", c.getServiceDefinitionMapsByDefinitionID)
@@ -370,3 +375,79 @@ func (c DefinitionsController) definitionServiceMaps(w http.ResponseWriter, r *h
 
 	render.Respond(w, r, results)
 }
+
+func (c DefinitionsController) previewDefinitionServiceMap(w http.ResponseWriter, r *http.Request) {
+	var m eve.DefinitionServiceMap
+	if err := json.ParseBody(r, &m); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.manager.PreviewDefinitionServiceMap(r.Context(), m)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+func (c DefinitionsController) previewDefinitionJobMap(w http.ResponseWriter, r *http.Request) {
+	var m eve.DefinitionJobMap
+	if err := json.ParseBody(r, &m); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.manager.PreviewDefinitionJobMap(r.Context(), m)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+func (c DefinitionsController) definitionRevisions(w http.ResponseWriter, r *http.Request) {
+	definitionID, err := strconv.Atoi(chi.URLParam(r, "definition"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid definition route parameter, required int value"))
+		return
+	}
+
+	query, err := historyQuery(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	results, err := c.manager.DefinitionRevisions(r.Context(), definitionID, query)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, results)
+}
+
+func (c DefinitionsController) restoreDefinitionRevision(w http.ResponseWriter, r *http.Request) {
+	definitionID, err := strconv.Atoi(chi.URLParam(r, "definition"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid definition route parameter, required int value"))
+		return
+	}
+
+	revision, err := strconv.Atoi(chi.URLParam(r, "revision"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid revision route parameter, required int value"))
+		return
+	}
+
+	result, err := c.manager.RestoreDefinitionRevision(r.Context(), definitionID, revision)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
@@ -22,6 +22,7 @@ func NewDeploymentPlansController(planGenerator *plans.PlanGenerator) *Deploymen
 
 func (c DeploymentPlansController) Setup(r *Routers) {
 	r.Auth.Post("/deployment-plans", c.createDeploymentPlan)
+	r.Auth.Post("/restarts", c.restart)
 }
 
 func (c DeploymentPlansController) createDeploymentPlan(w http.ResponseWriter, r *http.Request) {
@@ -44,3 +45,29 @@ func (c DeploymentPlansController) createDeploymentPlan(w http.ResponseWriter, r
 	}
 	render.Respond(w, r, options)
 }
+
+func (c DeploymentPlansController) restart(w http.ResponseWriter, r *http.Request) {
+	var restart eve.ServiceRestart
+	if err := json.ParseBody(r, &restart); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	restarts, err := c.planGenerator.RestartServices(r.Context(), restart)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Status(r, restartStatus(restarts))
+	render.Respond(w, r, restarts)
+}
+
+func restartStatus(restarts []*eve.DeploymentPlanOptions) int {
+	for _, x := range restarts {
+		if len(x.Messages) > 0 {
+			return http.StatusPartialContent
+		}
+	}
+	return http.StatusAccepted
+}
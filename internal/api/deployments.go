@@ -2,25 +2,61 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
 
 	"github.com/unanet/eve/internal/service/crud"
+	"github.com/unanet/eve/internal/service/plans"
+	"github.com/unanet/eve/internal/service/progress"
+	"github.com/unanet/eve/pkg/eve"
 )
 
 type DeploymentsController struct {
-	manager *crud.Manager
+	manager       *crud.Manager
+	planGenerator *plans.PlanGenerator
+	queue         *plans.Queue
+	broker        *progress.Broker
 }
 
-func NewDeploymentsController(manager *crud.Manager) *DeploymentsController {
+func NewDeploymentsController(manager *crud.Manager, planGenerator *plans.PlanGenerator, queue *plans.Queue, broker *progress.Broker) *DeploymentsController {
 	return &DeploymentsController{
-		manager: manager,
+		manager:       manager,
+		planGenerator: planGenerator,
+		queue:         queue,
+		broker:        broker,
 	}
 }
 
 func (c DeploymentsController) Setup(r *Routers) {
+	r.Auth.Get("/deployments", c.deployments)
 	r.Auth.Get("/deployments/{deployment}", c.deployment)
+	r.Auth.Post("/deployments/{deployment}/rollback", c.rollback)
+	r.Auth.Post("/deployments/{deployment}/cancel", c.cancel)
+	r.Auth.Get("/deployments/{deployment}/callbacks", c.callbacks)
+	r.Auth.Get("/deployments/{deployment}/events", c.events)
+	r.Auth.Post("/deployments/{deployment}/callbacks/{callback}/redeliver", c.redeliver)
+}
+
+func (c DeploymentsController) deployments(w http.ResponseWriter, r *http.Request) {
+	query, err := deploymentQuery(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	page, err := c.manager.Deployments(r.Context(), query)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, page)
 }
 
 func (c DeploymentsController) deployment(w http.ResponseWriter, r *http.Request) {
@@ -34,3 +70,115 @@ func (c DeploymentsController) deployment(w http.ResponseWriter, r *http.Request
 
 	render.Respond(w, r, deployment)
 }
+
+func (c DeploymentsController) rollback(w http.ResponseWriter, r *http.Request) {
+	var rollback eve.DeploymentRollback
+	if err := json.ParseBody(r, &rollback); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	options, err := c.planGenerator.QueueRollback(r.Context(), chi.URLParam(r, "deployment"), rollback)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	if len(options.Messages) > 0 {
+		render.Status(r, http.StatusPartialContent)
+	} else {
+		render.Status(r, http.StatusAccepted)
+	}
+	render.Respond(w, r, options)
+}
+
+func (c DeploymentsController) cancel(w http.ResponseWriter, r *http.Request) {
+	var cancel eve.DeploymentCancel
+	if err := json.ParseBody(r, &cancel); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	deployment, err := c.queue.CancelDeployment(r.Context(), chi.URLParam(r, "deployment"), cancel)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, deployment)
+}
+
+func (c DeploymentsController) callbacks(w http.ResponseWriter, r *http.Request) {
+	callbacks, err := c.manager.DeploymentCallbacks(r.Context(), chi.URLParam(r, "deployment"))
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, callbacks)
+}
+
+func (c DeploymentsController) redeliver(w http.ResponseWriter, r *http.Request) {
+	callbackID, err := strconv.Atoi(chi.URLParam(r, "callback"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid callback route parameter, required int value"))
+		return
+	}
+
+	callback, err := c.queue.RedeliverCallback(r.Context(), chi.URLParam(r, "deployment"), callbackID)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.Respond(w, r, callback)
+}
+
+// events streams the deployment's progress until it completes, a deployment that's already finished just gets its
+// completion
+func (c DeploymentsController) events(w http.ResponseWriter, r *http.Request) {
+	deploymentID, err := uuid.FromString(chi.URLParam(r, "deployment"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid deployment route parameter, required uuid value"))
+		return
+	}
+
+	lastID, err := lastEventID(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	// subscribe before looking at the state so the completion can't slip in between, a reconnecting client gets
+	// what it missed first
+	sub, initial := c.broker.SubscribeSince(func(p eve.DeploymentProgress) bool {
+		return uuid.Equal(p.DeploymentID, deploymentID)
+	}, lastID)
+
+	deployment, err := c.manager.Deployment(r.Context(), deploymentID.String())
+	if err != nil {
+		sub.Close()
+		render.Respond(w, r, err)
+		return
+	}
+
+	if eve.ToDataDeploymentState(deployment.State).Terminal() {
+		var messages []string
+		for _, x := range deployment.Messages {
+			messages = append(messages, x.Message)
+		}
+		initial = append(initial, eve.DeploymentProgress{
+			Type:         eve.DeploymentProgressComplete,
+			DeploymentID: deployment.ID,
+			State:        deployment.State,
+			Artifacts:    deployment.Results,
+			Messages:     messages,
+			CreatedAt:    deployment.UpdatedAt,
+		})
+	}
+
+	streamProgress(w, r, sub, initial, func(p eve.DeploymentProgress) bool {
+		return p.Type == eve.DeploymentProgressComplete
+	})
+}
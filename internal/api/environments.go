@@ -5,6 +5,8 @@ import (
 	"strconv"
 
 	"github.com/unanet/eve/internal/service/crud"
+	"github.com/unanet/eve/internal/service/plans"
+	"github.com/unanet/eve/internal/service/progress"
 	"github.com/unanet/eve/pkg/eve"
 	"github.com/unanet/go/pkg/errors"
 	"github.com/unanet/go/pkg/json"
@@ -14,12 +16,16 @@ import (
 )
 
 type EnvironmentController struct {
-	manager *crud.Manager
+	manager       *crud.Manager
+	planGenerator *plans.PlanGenerator
+	broker        *progress.Broker
 }
 
-func NewEnvironmentController(manager *crud.Manager) *EnvironmentController {
+func NewEnvironmentController(manager *crud.Manager, planGenerator *plans.PlanGenerator, broker *progress.Broker) *EnvironmentController {
 	return &EnvironmentController{
-		manager: manager,
+		manager:       manager,
+		planGenerator: planGenerator,
+		broker:        broker,
 	}
 }
 
@@ -28,6 +34,8 @@ func (c EnvironmentController) Setup(r *Routers) {
 	r.Auth.Post("/environments", c.createEnvironment)
 	r.Auth.Get("/environments/{environment}", c.environment)
 	r.Auth.Post("/environments/{environment}", c.updateEnvironment)
+	r.Auth.Post("/environments/{environment}/scale", c.scale)
+	r.Auth.Get("/environments/{environment}/events", c.events)
 	//r.Delete("/environments/{environment}", c.deleteEnvironment)
 }
 
@@ -112,3 +120,43 @@ func (c EnvironmentController) deleteEnvironment(w http.ResponseWriter, r *http.
 
 	render.Status(r, http.StatusNoContent)
 }
+
+func (c EnvironmentController) scale(w http.ResponseWriter, r *http.Request) {
+	var scale eve.ServiceScale
+	if err := json.ParseBody(r, &scale); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.planGenerator.ScaleEnvironment(r.Context(), chi.URLParam(r, "environment"), scale)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+// events streams the progress of every deployment in the environment, optionally just the ones for a namespace
+func (c EnvironmentController) events(w http.ResponseWriter, r *http.Request) {
+	environment, err := c.manager.Environment(r.Context(), chi.URLParam(r, "environment"))
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	lastID, err := lastEventID(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	sub, missed := c.broker.SubscribeSince(func(p eve.DeploymentProgress) bool {
+		return p.Environment == environment.Name && (len(namespace) == 0 || p.Namespace == namespace)
+	}, lastID)
+
+	streamProgress(w, r, sub, missed, func(eve.DeploymentProgress) bool {
+		return false
+	})
+}
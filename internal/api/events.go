@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/internal/service/progress"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+const (
+	// streamLifetime keeps a stream inside the server's write timeout, EventSource clients reconnect on their own
+	// after streamRetry and send the id of the last event they saw, which is replayed from with lastEventID
+	streamLifetime  = 25 * time.Second
+	streamKeepAlive = 10 * time.Second
+	streamRetry     = time.Second
+)
+
+// streamProgress writes the initial events and then everything the subscription receives as server-sent events, until
+// done returns true, the client goes away or the stream has been open for streamLifetime
+func streamProgress(w http.ResponseWriter, r *http.Request, sub *progress.Subscription, initial []eve.DeploymentProgress, done func(eve.DeploymentProgress) bool) {
+	defer sub.Close()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		render.Respond(w, r, errors.NewRestError(http.StatusInternalServerError, "streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+
+	for _, x := range initial {
+		if writeProgress(w, x) != nil || done(x) {
+			flusher.Flush()
+			return
+		}
+	}
+	flusher.Flush()
+
+	lifetime := time.NewTimer(streamLifetime)
+	defer lifetime.Stop()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-lifetime.C:
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case p := <-sub.C:
+			if err := writeProgress(w, p); err != nil || done(p) {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeProgress(w http.ResponseWriter, p eve.DeploymentProgress) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", progress.EventID(p), p.Type, b)
+	return err
+}
+
+// lastEventID is the id of the last event a reconnecting client saw, or 0 for a new stream
+func lastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.BadRequest("invalid Last-Event-ID header, required int value")
+	}
+	return id, nil
+}
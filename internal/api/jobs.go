@@ -30,6 +30,8 @@ func (c JobController) Setup(r *Routers) {
 	r.Auth.Post("/jobs/{job}", c.updateJob)
 	r.Auth.Delete("/jobs/{job}", c.delete)
 	r.Auth.Get("/jobs/{job}/metadata", c.getJobMetadata)
+	r.Auth.Get("/jobs/{job}/metadata/explain", c.getJobMetadataExplanation)
+	r.Auth.Get("/jobs/{job}/history", c.getJobHistory)
 	r.Auth.Get("/jobs/{job}/metadata-maps", c.getJobMetadataMaps)
 }
 
@@ -153,3 +155,42 @@ func (c JobController) delete(w http.ResponseWriter, r *http.Request) {
 
 	render.Status(r, http.StatusNoContent)
 }
+
+func (c JobController) getJobHistory(w http.ResponseWriter, r *http.Request) {
+	job := chi.URLParam(r, "job")
+	jobID, err := strconv.Atoi(job)
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid job route parameter, required int value"))
+		return
+	}
+
+	query, err := historyQuery(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.manager.JobVersionHistory(r.Context(), jobID, query)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+func (c JobController) getJobMetadataExplanation(w http.ResponseWriter, r *http.Request) {
+	job := chi.URLParam(r, "job")
+	jobID, err := strconv.Atoi(job)
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid job route parameter, required int value"))
+		return
+	}
+	result, err := c.manager.JobMetadataExplanation(r.Context(), jobID)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
@@ -41,6 +41,8 @@ func (c MetadataController) Setup(r *Routers) {
 	r.Auth.Put("/metadata/service-maps", c.updateMetadataServiceMap)
 	r.Auth.Post("/metadata/service-maps", c.createMetadataServiceMaps)
 	r.Auth.Delete("/metadata/service-maps", c.deleteMetadataServiceMap)
+	r.Auth.Post("/metadata/service-maps/preview", c.previewMetadataServiceMap)
+	r.Auth.Post("/metadata/job-maps/preview", c.previewMetadataJobMap)
 
 	r.Auth.Put("/metadata/{metadata}/service-maps", c.upsertMetadataServiceMap)
 	r.Auth.Delete("/metadata/{metadata}/service-maps/{description}", c.deleteServiceMetadataMap)
@@ -50,6 +52,9 @@ func (c MetadataController) Setup(r *Routers) {
 	r.Auth.Delete("/metadata/{metadata}/job-maps/{description}", c.deleteJobMetadataMap)
 	r.Auth.Get("/metadata/{metadata}/job-maps", c.getJobMetadataMapsByMetadataID)
 
+	r.Auth.Get("/metadata/{metadata}/history", c.metadataRevisions)
+	r.Auth.Post("/metadata/{metadata}/restore/{revision}", c.restoreMetadataRevision)
+
 	r.Auth.Get("/metadata-history", c.metadataHistory)
 }
 
@@ -92,6 +97,50 @@ func (c MetadataController) metadataHistory(w http.ResponseWriter, r *http.Reque
 	render.Respond(w, r, results)
 }
 
+func (c MetadataController) metadataRevisions(w http.ResponseWriter, r *http.Request) {
+	metadataID, err := strconv.Atoi(chi.URLParam(r, "metadata"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid metadata route parameter, required int value"))
+		return
+	}
+
+	query, err := historyQuery(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	results, err := c.manager.MetadataRevisions(r.Context(), metadataID, query)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, results)
+}
+
+func (c MetadataController) restoreMetadataRevision(w http.ResponseWriter, r *http.Request) {
+	metadataID, err := strconv.Atoi(chi.URLParam(r, "metadata"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid metadata route parameter, required int value"))
+		return
+	}
+
+	revision, err := strconv.Atoi(chi.URLParam(r, "revision"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid revision route parameter, required int value"))
+		return
+	}
+
+	result, err := c.manager.RestoreMetadataRevision(r.Context(), metadataID, revision)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
 func (c MetadataController) upsertMetadata(w http.ResponseWriter, r *http.Request) {
 	var m eve.Metadata
 	if err := json.ParseBody(r, &m); err != nil {
@@ -410,3 +459,35 @@ func (c MetadataController) deleteMetadataServiceMap(w http.ResponseWriter, r *h
 
 	render.Status(r, http.StatusNoContent)
 }
+
+func (c MetadataController) previewMetadataServiceMap(w http.ResponseWriter, r *http.Request) {
+	var m eve.MetadataServiceMap
+	if err := json.ParseBody(r, &m); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.manager.PreviewMetadataServiceMap(r.Context(), m)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+func (c MetadataController) previewMetadataJobMap(w http.ResponseWriter, r *http.Request) {
+	var m eve.MetadataJobMap
+	if err := json.ParseBody(r, &m); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.manager.PreviewMetadataJobMap(r.Context(), m)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
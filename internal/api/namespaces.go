@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/unanet/eve/internal/service/crud"
+	"github.com/unanet/eve/internal/service/plans"
 	"github.com/unanet/eve/pkg/eve"
 	"github.com/unanet/go/pkg/errors"
 	"github.com/unanet/go/pkg/json"
@@ -14,12 +15,14 @@ import (
 )
 
 type NamespaceController struct {
-	manager *crud.Manager
+	manager       *crud.Manager
+	planGenerator *plans.PlanGenerator
 }
 
-func NewNamespaceController(manager *crud.Manager) *NamespaceController {
+func NewNamespaceController(manager *crud.Manager, planGenerator *plans.PlanGenerator) *NamespaceController {
 	return &NamespaceController{
-		manager: manager,
+		manager:       manager,
+		planGenerator: planGenerator,
 	}
 }
 
@@ -32,6 +35,7 @@ func (c NamespaceController) Setup(r *Routers) {
 	r.Auth.Get("/namespaces/{namespace}/services/{service}", c.service)
 	r.Auth.Get("/namespaces/{namespace}/jobs", c.namespaceJobs)
 	r.Auth.Get("/namespaces/{namespace}/jobs/{job}", c.job)
+	r.Auth.Post("/namespaces/{namespace}/scale", c.scale)
 	//r.Auth.Delete("/namespaces/{namespace}", c.deleteNamespace)
 }
 
@@ -190,3 +194,19 @@ func (c NamespaceController) deleteNamespace(w http.ResponseWriter, r *http.Requ
 
 	render.Status(r, http.StatusNoContent)
 }
+
+func (c NamespaceController) scale(w http.ResponseWriter, r *http.Request) {
+	var scale eve.ServiceScale
+	if err := json.ParseBody(r, &scale); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.planGenerator.ScaleNamespace(r.Context(), chi.URLParam(r, "namespace"), scale)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
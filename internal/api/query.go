@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func queryInt(r *http.Request, key string) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.BadRequest(fmt.Sprintf("invalid %s query parameter, required int value", key))
+	}
+	return intValue, nil
+}
+
+func queryTime(r *http.Request, key string) (*time.Time, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	timeValue, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.BadRequest(fmt.Sprintf("invalid %s query parameter, required RFC3339 timestamp", key))
+	}
+	return &timeValue, nil
+}
+
+func historyQuery(r *http.Request) (query eve.HistoryQuery, err error) {
+	if query.Limit, err = queryInt(r, "limit"); err != nil {
+		return
+	}
+	if query.Offset, err = queryInt(r, "offset"); err != nil {
+		return
+	}
+	if query.From, err = queryTime(r, "from"); err != nil {
+		return
+	}
+	if query.To, err = queryTime(r, "to"); err != nil {
+		return
+	}
+	return
+}
+
+func deploymentQuery(r *http.Request) (query eve.DeploymentQuery, err error) {
+	q := r.URL.Query()
+	query.Environment = q.Get("environment")
+	query.Namespace = q.Get("namespace")
+	query.User = q.Get("user")
+	query.State = q.Get("state")
+	query.Type = q.Get("type")
+	query.Artifact = q.Get("artifact")
+	query.CronID = q.Get("cron")
+	query.Cursor = q.Get("cursor")
+
+	switch strings.ToLower(q.Get("sort")) {
+	case "", "desc":
+	case "asc":
+		query.Ascending = true
+	default:
+		err = errors.BadRequest("invalid sort query parameter, required asc or desc")
+		return
+	}
+
+	if query.Limit, err = queryInt(r, "limit"); err != nil {
+		return
+	}
+	if query.From, err = queryTime(r, "from"); err != nil {
+		return
+	}
+	if query.To, err = queryTime(r, "to"); err != nil {
+		return
+	}
+	return
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/internal/service/crud"
+	"github.com/unanet/eve/internal/service/plans"
+)
+
+type QueueController struct {
+	manager *crud.Manager
+	queue   *plans.Queue
+}
+
+func NewQueueController(manager *crud.Manager, queue *plans.Queue) *QueueController {
+	return &QueueController{
+		manager: manager,
+		queue:   queue,
+	}
+}
+
+func (c QueueController) Setup(r *Routers) {
+	r.Auth.Get("/queue/dead-letters", c.deadLetters)
+	r.Auth.Post("/queue/dead-letters/{deadLetter}/replay", c.replay)
+}
+
+func (c QueueController) deadLetters(w http.ResponseWriter, r *http.Request) {
+	query, err := historyQuery(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.manager.DeadLetters(r.Context(), query)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+func (c QueueController) replay(w http.ResponseWriter, r *http.Request) {
+	deadLetterID, err := strconv.Atoi(chi.URLParam(r, "deadLetter"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid deadLetter route parameter, required int value"))
+		return
+	}
+
+	result, err := c.queue.ReplayDeadLetter(r.Context(), deadLetterID)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.Respond(w, r, result)
+}
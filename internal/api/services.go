@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/unanet/eve/internal/service/crud"
+	"github.com/unanet/eve/internal/service/plans"
 	"github.com/unanet/eve/pkg/eve"
 	"github.com/unanet/go/pkg/errors"
 	"github.com/unanet/go/pkg/json"
@@ -14,12 +15,14 @@ import (
 )
 
 type ServiceController struct {
-	manager *crud.Manager
+	manager       *crud.Manager
+	planGenerator *plans.PlanGenerator
 }
 
-func NewServiceController(manager *crud.Manager) *ServiceController {
+func NewServiceController(manager *crud.Manager, planGenerator *plans.PlanGenerator) *ServiceController {
 	return &ServiceController{
-		manager: manager,
+		manager:       manager,
+		planGenerator: planGenerator,
 	}
 }
 
@@ -30,6 +33,11 @@ func (c ServiceController) Setup(r *Routers) {
 	r.Auth.Post("/services/{service}", c.updateService)
 	r.Auth.Delete("/services/{service}", c.delete)
 	r.Auth.Get("/services/{service}/metadata", c.getServiceMetadata)
+	r.Auth.Get("/services/{service}/metadata/explain", c.getServiceMetadataExplanation)
+	r.Auth.Get("/services/{service}/history", c.getServiceHistory)
+	r.Auth.Post("/services/{service}/scale", c.scale)
+	r.Auth.Get("/services/{service}/scale-history", c.getServiceScaleHistory)
+	r.Auth.Post("/services/{service}/restart", c.restart)
 	r.Auth.Get("/services/{service}/metadata-maps", c.getServiceMetadataMaps)
 	r.Auth.Get("/services/{service}/definitions", c.getServiceDefinitionResult)
 	r.Auth.Get("/services/{service}/definition-maps", c.getServiceDefinitions)
@@ -186,3 +194,110 @@ func (c ServiceController) delete(w http.ResponseWriter, r *http.Request) {
 
 	render.Status(r, http.StatusNoContent)
 }
+
+func (c ServiceController) getServiceHistory(w http.ResponseWriter, r *http.Request) {
+	service := chi.URLParam(r, "service")
+	serviceID, err := strconv.Atoi(service)
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid service route parameter, required int value"))
+		return
+	}
+
+	query, err := historyQuery(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.manager.ServiceVersionHistory(r.Context(), serviceID, query)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+func (c ServiceController) getServiceScaleHistory(w http.ResponseWriter, r *http.Request) {
+	service := chi.URLParam(r, "service")
+	serviceID, err := strconv.Atoi(service)
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid service route parameter, required int value"))
+		return
+	}
+
+	query, err := historyQuery(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.manager.ServiceScaleHistory(r.Context(), serviceID, query)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+func (c ServiceController) scale(w http.ResponseWriter, r *http.Request) {
+	serviceID, err := strconv.Atoi(chi.URLParam(r, "service"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid service route parameter, required int value"))
+		return
+	}
+
+	var scale eve.ServiceScale
+	if err := json.ParseBody(r, &scale); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.planGenerator.ScaleService(r.Context(), serviceID, scale)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+func (c ServiceController) restart(w http.ResponseWriter, r *http.Request) {
+	serviceID, err := strconv.Atoi(chi.URLParam(r, "service"))
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid service route parameter, required int value"))
+		return
+	}
+
+	var restart eve.ServiceRestart
+	if err := json.ParseBody(r, &restart); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	restarts, err := c.planGenerator.RestartService(r.Context(), serviceID, restart)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Status(r, restartStatus(restarts))
+	render.Respond(w, r, restarts)
+}
+
+func (c ServiceController) getServiceMetadataExplanation(w http.ResponseWriter, r *http.Request) {
+	service := chi.URLParam(r, "service")
+	serviceID, err := strconv.Atoi(service)
+	if err != nil {
+		render.Respond(w, r, errors.BadRequest("invalid service route parameter, required int value"))
+		return
+	}
+	result, err := c.manager.ServiceMetadataExplanation(r.Context(), serviceID)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+
+	"github.com/unanet/eve/internal/service/crud"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+type SubscriptionController struct {
+	manager *crud.Manager
+}
+
+func NewSubscriptionController(manager *crud.Manager) *SubscriptionController {
+	return &SubscriptionController{
+		manager: manager,
+	}
+}
+
+func (c SubscriptionController) Setup(r *Routers) {
+	r.Auth.Get("/subscriptions", c.subscriptions)
+	r.Auth.Post("/subscriptions", c.createSubscription)
+	r.Auth.Get("/subscriptions/{subscription}", c.subscription)
+	r.Auth.Put("/subscriptions/{subscription}", c.updateSubscription)
+	r.Auth.Delete("/subscriptions/{subscription}", c.deleteSubscription)
+}
+
+func subscriptionID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "subscription"))
+	if err != nil {
+		return 0, errors.BadRequest("invalid subscription route parameter, required int value")
+	}
+	return id, nil
+}
+
+func (c SubscriptionController) subscriptions(w http.ResponseWriter, r *http.Request) {
+	results, err := c.manager.Subscriptions(r.Context())
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, results)
+}
+
+func (c SubscriptionController) subscription(w http.ResponseWriter, r *http.Request) {
+	id, err := subscriptionID(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	result, err := c.manager.Subscription(r.Context(), id)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, result)
+}
+
+func (c SubscriptionController) createSubscription(w http.ResponseWriter, r *http.Request) {
+	var m eve.Subscription
+	if err := json.ParseBody(r, &m); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	err := c.manager.CreateSubscription(r.Context(), &m)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.Respond(w, r, m)
+}
+
+func (c SubscriptionController) updateSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := subscriptionID(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	var m eve.Subscription
+	if err := json.ParseBody(r, &m); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+	m.ID = id
+
+	err = c.manager.UpdateSubscription(r.Context(), &m)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Respond(w, r, m)
+}
+
+func (c SubscriptionController) deleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := subscriptionID(r)
+	if err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	if err := c.manager.DeleteSubscription(r.Context(), id); err != nil {
+		render.Respond(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusNoContent)
+}
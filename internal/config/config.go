@@ -12,21 +12,28 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/unanet/eve/pkg/artifactory"
+	"github.com/unanet/eve/pkg/envelope"
+	"github.com/unanet/eve/pkg/notify"
 	"github.com/unanet/eve/pkg/scm/github"
 	"github.com/unanet/eve/pkg/scm/gitlab"
+	"github.com/unanet/eve/pkg/secrets"
 )
 
 var (
-	flagConfig *FlagConfig
-	config     *Config
-	dbConfig   *DBConfig
-	mutex      = sync.Mutex{}
+	flagConfig       *FlagConfig
+	config           *Config
+	dbConfig         *DBConfig
+	encryptionConfig *EncryptionConfig
+	mutex            = sync.Mutex{}
 )
 
 type LogConfig = log.Config
 type ArtifactoryConfig = artifactory.Config
 type GitLabConfig = gitlab.Config
 type GitHubConfig = github.Config
+type NotifyConfig = notify.Config
+type SecretsConfig = secrets.Config
+type EncryptionConfig = envelope.Config
 
 type DBConfig struct {
 	DBHost              string        `envconfig:"DB_HOST" default:"localhost"`
@@ -53,27 +60,46 @@ type Config struct {
 	ArtifactoryConfig
 	GitLabConfig
 	GitHubConfig
-	Identity               IdentityConfig
-	LocalDev               bool          `envconfig:"LOCAL_DEV" default:"false"`
-	ApiQUrl                string        `envconfig:"API_Q_URL" required:"true"`
-	SourceControlProvider  string        `envconfig:"SCM_PROVIDER" default:"gitlab"`
-	ApiQWaitTimeSecond     int64         `envconfig:"API_Q_WAIT_TIME_SECOND" default:"20"`
-	ApiQVisibilityTimeout  int64         `envconfig:"API_Q_VISIBILITY_TIMEOUT" default:"3600"`
-	ApiQMaxNumberOfMessage int64         `envconfig:"API_Q_MAX_NUMBER_OF_MESSAGE" default:"10"`
-	ApiQWorkerTimeout      time.Duration `envconfig:"API_Q_WORKER_TIMEOUT" default:"60s"`
-	CronTimeout            time.Duration `envconfig:"CRON_TIMEOUT" default:"120s"`
-	HttpCallbackTimeout    time.Duration `envconfig:"HTTP_CALLBACK_TIMEOUT" default:"8s"`
-	S3Bucket               string        `envconfig:"S3_BUCKET" required:"true"`
-	AWSRegion              string        `envconfig:"AWS_REGION" required:"true"`
-	Port                   int           `envconfig:"PORT" default:"8080"`
-	MetricsPort            int           `envconfig:"METRICS_PORT" default:"3001"`
-	ServiceName            string        `envconfig:"SERVICE_NAME" default:"eve"`
-	AdminToken             string        `envconfig:"ADMIN_TOKEN" required:"true"`
+	NotifyConfig
+	SecretsConfig
+	Identity                   IdentityConfig
+	LocalDev                   bool              `envconfig:"LOCAL_DEV" default:"false"`
+	ApiQUrl                    string            `envconfig:"API_Q_URL" required:"true"`
+	QueueBackend               string            `envconfig:"QUEUE_BACKEND" default:"sqs"`
+	SourceControlProvider      string            `envconfig:"SCM_PROVIDER" default:"gitlab"`
+	ApiQWaitTimeSecond         int64             `envconfig:"API_Q_WAIT_TIME_SECOND" default:"20"`
+	ApiQVisibilityTimeout      int64             `envconfig:"API_Q_VISIBILITY_TIMEOUT" default:"3600"`
+	ApiQMaxNumberOfMessage     int64             `envconfig:"API_Q_MAX_NUMBER_OF_MESSAGE" default:"10"`
+	ApiQWorkerTimeout          time.Duration     `envconfig:"API_Q_WORKER_TIMEOUT" default:"60s"`
+	ApiQWorkerConcurrency      int               `envconfig:"API_Q_WORKER_CONCURRENCY" default:"10"`
+	ApiQCommandConcurrency     map[string]int    `envconfig:"API_Q_COMMAND_CONCURRENCY"`
+	ApiQMaxAttempts            int               `envconfig:"API_Q_MAX_ATTEMPTS" default:"5"`
+	ApiQRetryBaseDelay         time.Duration     `envconfig:"API_Q_RETRY_BASE_DELAY" default:"30s"`
+	ApiQRetryMaxDelay          time.Duration     `envconfig:"API_Q_RETRY_MAX_DELAY" default:"15m"`
+	CronTimeout                time.Duration     `envconfig:"CRON_TIMEOUT" default:"120s"`
+	DeploymentDeadline         time.Duration     `envconfig:"DEPLOYMENT_DEADLINE" default:"2h"`
+	HttpCallbackTimeout        time.Duration     `envconfig:"HTTP_CALLBACK_TIMEOUT" default:"8s"`
+	HttpCallbackSecret         string            `envconfig:"HTTP_CALLBACK_SECRET"`
+	HttpCallbackSecrets        map[string]string `envconfig:"HTTP_CALLBACK_SECRETS"`
+	HttpCallbackMaxAttempts    int               `envconfig:"HTTP_CALLBACK_MAX_ATTEMPTS" default:"8"`
+	HttpCallbackRetryBaseDelay time.Duration     `envconfig:"HTTP_CALLBACK_RETRY_BASE_DELAY" default:"30s"`
+	HttpCallbackRetryMaxDelay  time.Duration     `envconfig:"HTTP_CALLBACK_RETRY_MAX_DELAY" default:"1h"`
+	S3Bucket                   string            `envconfig:"S3_BUCKET"`
+	PlanStorage                string            `envconfig:"PLAN_STORAGE" default:"s3"`
+	PlanStorageDir             string            `envconfig:"PLAN_STORAGE_DIR" default:"/tmp/eve/plans"`
+	AWSRegion                  string            `envconfig:"AWS_REGION"`
+	Port                       int               `envconfig:"PORT" default:"8080"`
+	MetricsPort                int               `envconfig:"METRICS_PORT" default:"3001"`
+	ServiceName                string            `envconfig:"SERVICE_NAME" default:"eve"`
+	TracingEndpoint            string            `envconfig:"TRACING_ENDPOINT"`
+	TracingInsecure            bool              `envconfig:"TRACING_INSECURE" default:"false"`
+	AdminToken                 string            `envconfig:"ADMIN_TOKEN" required:"true"`
 }
 
 type FlagConfig struct {
-	MigrateFlag bool `envconfig:"MIGRATE_FLAG" default:"false"`
-	ServerFlag  bool `envconfig:"SERVER_FLAG" default:"true"`
+	MigrateFlag            bool `envconfig:"MIGRATE_FLAG" default:"false"`
+	RotateMetadataKeysFlag bool `envconfig:"ROTATE_METADATA_KEYS_FLAG" default:"false"`
+	ServerFlag             bool `envconfig:"SERVER_FLAG" default:"true"`
 }
 
 func GetDBConfig() DBConfig {
@@ -91,6 +117,22 @@ func GetDBConfig() DBConfig {
 	return *dbConfig
 }
 
+// GetEncryptionConfig is separate from Config so the metadata keys can be rotated without the rest of the server config
+func GetEncryptionConfig() EncryptionConfig {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if encryptionConfig != nil {
+		return *encryptionConfig
+	}
+	c := EncryptionConfig{}
+	err := envconfig.Process("EVE", &c)
+	if err != nil {
+		log.Logger.Panic("Unable to Load Config", zap.Error(err))
+	}
+	encryptionConfig = &c
+	return *encryptionConfig
+}
+
 func GetConfig() Config {
 	mutex.Lock()
 	defer mutex.Unlock()
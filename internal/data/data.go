@@ -16,12 +16,12 @@ import (
 )
 
 type Repo struct {
-	db *sqlx.DB
+	db tracedDB
 }
 
 func NewRepo(db *sqlx.DB) *Repo {
 	return &Repo{
-		db: db,
+		db: tracedDB{DB: db},
 	}
 }
 
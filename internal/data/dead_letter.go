@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/unanet/go/pkg/errors"
+)
+
+type DeadLetterMessage struct {
+	ID           int           `db:"id"`
+	Queue        string        `db:"queue"`
+	MessageID    string        `db:"message_id"`
+	GroupID      string        `db:"group_id"`
+	Command      string        `db:"command"`
+	EveID        uuid.NullUUID `db:"eve_id"`
+	Body         string        `db:"body"`
+	Error        string        `db:"error"`
+	ReceiveCount int           `db:"receive_count"`
+	ReplayedAt   sql.NullTime  `db:"replayed_at"`
+	CreatedAt    sql.NullTime  `db:"created_at"`
+}
+
+type DeadLetterMessages []DeadLetterMessage
+
+func (r *Repo) CreateDeadLetterMessage(ctx context.Context, m *DeadLetterMessage) error {
+	m.CreatedAt.Time = time.Now().UTC()
+	m.CreatedAt.Valid = true
+	err := r.db.QueryRowxContext(ctx, `
+		insert into dead_letter_message(queue, message_id, group_id, command, eve_id, body, error, receive_count, created_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		returning id
+	`, m.Queue, m.MessageID, m.GroupID, m.Command, m.EveID, m.Body, m.Error, m.ReceiveCount, m.CreatedAt).
+		Scan(&m.ID)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	return nil
+}
+
+func (r *Repo) DeadLetterMessageByID(ctx context.Context, id int) (*DeadLetterMessage, error) {
+	var m DeadLetterMessage
+	row := r.db.QueryRowxContext(ctx, "select * from dead_letter_message where id = $1", id)
+	err := row.StructScan(&m)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, NotFoundErrorf("dead letter message with id: %d, not found", id)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	return &m, nil
+}
+
+// DeadLetterMessages returns the dead lettered messages newest first
+func (r *Repo) DeadLetterMessages(ctx context.Context, limit int, offset int, whereArgs ...WhereArg) (DeadLetterMessages, error) {
+	esql, args := CheckWhereArgs("select * from dead_letter_message", whereArgs)
+	esql = fmt.Sprintf("%s order by created_at desc, id desc limit $%d offset $%d", esql, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryxContext(ctx, esql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var messages DeadLetterMessages
+	for rows.Next() {
+		var m DeadLetterMessage
+		err = rows.StructScan(&m)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
+
+// UpdateDeadLetterMessageReplayed sets or clears replayed_at, setting it only succeeds when the message hasn't already been replayed
+func (r *Repo) UpdateDeadLetterMessageReplayed(ctx context.Context, id int, replayed bool) error {
+	var result sql.Result
+	var err error
+	if replayed {
+		result, err = r.db.ExecContext(ctx, "update dead_letter_message set replayed_at = $1 where id = $2 and replayed_at is null", time.Now().UTC(), id)
+	} else {
+		result, err = r.db.ExecContext(ctx, "update dead_letter_message set replayed_at = null where id = $1", id)
+	}
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	if affected == 0 {
+		return NotFoundErrorf("dead letter message with id: %d, not found or already replayed", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"fmt"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+)
+
+type DefinitionHistory struct {
+	ID               int          `db:"id"`
+	DefinitionID     int          `db:"definition_id"`
+	Description      string       `db:"description"`
+	DefinitionTypeID int          `db:"definition_type_id"`
+	Data             json.Object  `db:"data"`
+	Created          sql.NullTime `db:"created"`
+	CreatedBy        string       `db:"created_by"`
+	Deleted          sql.NullTime `db:"deleted"`
+	DeletedBy        *string      `db:"deleted_by"`
+}
+
+func (r *Repo) DefinitionRevision(ctx context.Context, definitionID int, revision int) (*DefinitionHistory, error) {
+	var d DefinitionHistory
+	err := r.db.QueryRowxContext(ctx, `
+		SELECT 
+			id,
+			definition_id,
+			description,
+			definition_type_id,
+			data,
+			created,
+			created_by,
+			deleted,
+			deleted_by
+		FROM definition_history 
+		WHERE definition_id = $1 AND id = $2
+	`, definitionID, revision).StructScan(&d)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, NotFoundErrorf("definition with id: %d has no revision: %d", definitionID, revision)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	return &d, nil
+}
+
+// DefinitionRevisions returns definition history newest first, a limit of 0 returns every matching revision
+func (r *Repo) DefinitionRevisions(ctx context.Context, limit int, offset int, whereArgs ...WhereArg) ([]DefinitionHistory, error) {
+	esql, args := CheckWhereArgs(`
+		SELECT 
+			id,
+			definition_id,
+			description,
+			definition_type_id,
+			data,
+			created,
+			created_by,
+			deleted,
+			deleted_by
+		FROM definition_history 
+	`, whereArgs)
+
+	esql = fmt.Sprintf("%s ORDER BY id DESC", esql)
+	if limit > 0 {
+		esql = fmt.Sprintf("%s LIMIT $%d OFFSET $%d", esql, len(args)+1, len(args)+2)
+		args = append(args, limit, offset)
+	}
+
+	rows, err := r.db.QueryxContext(ctx, esql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var dd []DefinitionHistory
+	for rows.Next() {
+		if rows.Err() != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		var d DefinitionHistory
+		err = rows.StructScan(&d)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		dd = append(dd, d)
+	}
+
+	return dd, nil
+}
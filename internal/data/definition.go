@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	goErrors "errors"
+	"github.com/jmoiron/sqlx"
 	"github.com/unanet/go/pkg/errors"
 	"github.com/unanet/go/pkg/json"
 	"time"
@@ -97,14 +98,16 @@ func (r *Repo) UpsertMergeDefinition(ctx context.Context, def *Definition) error
 		Valid: true,
 	}
 
-	err := r.db.QueryRowxContext(ctx, `
-	INSERT INTO definition(description, definition_type_id, data, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (description)
-		DO UPDATE SET data = definition.data || $3, updated_at = $5
-		RETURNING id, data, created_at
-	`, def.Description, def.DefinitionTypeID, def.Data, def.CreatedAt, def.UpdatedAt).
-		StructScan(def)
+	err := r.asUser(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowxContext(ctx, `
+		INSERT INTO definition(description, definition_type_id, data, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (description)
+			DO UPDATE SET data = definition.data || $3, updated_at = $5
+			RETURNING id, data, created_at
+		`, def.Description, def.DefinitionTypeID, def.Data, def.CreatedAt, def.UpdatedAt).
+			StructScan(def)
+	})
 
 	if err != nil {
 		return errors.Wrap(err)
@@ -124,16 +127,18 @@ func (r *Repo) UpsertDefinition(ctx context.Context, def *Definition) error {
 		Valid: true,
 	}
 
-	err := r.db.QueryRowxContext(ctx, `
-	
-	INSERT INTO definition(description, definition_type_id,  data, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (description)
-		DO UPDATE SET data = $3, updated_at = $5
-		RETURNING id, created_at
-	
-	`, def.Description, def.DefinitionTypeID, def.Data, def.CreatedAt, def.UpdatedAt).
-		StructScan(def)
+	err := r.asUser(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowxContext(ctx, `
+		
+		INSERT INTO definition(description, definition_type_id,  data, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (description)
+			DO UPDATE SET data = $3, updated_at = $5
+			RETURNING id, created_at
+		
+		`, def.Description, def.DefinitionTypeID, def.Data, def.CreatedAt, def.UpdatedAt).
+			StructScan(def)
+	})
 
 	if err != nil {
 		return errors.Wrap(err)
@@ -282,10 +287,12 @@ func (r *Repo) Definition(ctx context.Context) ([]Definition, error) {
 
 func (r *Repo) DeleteDefinitionKey(ctx context.Context, definitionID int, key string) (*Definition, error) {
 	var definition Definition
-	err := r.db.QueryRowxContext(ctx, `
-		UPDATE definition SET data = definition.data - $1 WHERE id = $2
-		RETURNING id, data, description,definition_type_id, created_at, updated_at
-	`, key, definitionID).StructScan(&definition)
+	err := r.asUser(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowxContext(ctx, `
+			UPDATE definition SET data = definition.data - $1 WHERE id = $2
+			RETURNING id, data, description,definition_type_id, created_at, updated_at
+		`, key, definitionID).StructScan(&definition)
+	})
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
@@ -294,23 +301,25 @@ func (r *Repo) DeleteDefinitionKey(ctx context.Context, definitionID int, key st
 }
 
 func (r *Repo) DeleteDefinition(ctx context.Context, definitionID int) error {
-	result, err := r.db.ExecContext(ctx, `
-		DELETE FROM definition WHERE id = $1
-	`, definitionID)
-	if err != nil {
-		return errors.Wrap(err)
-	}
+	return r.asUser(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM definition WHERE id = $1
+		`, definitionID)
+		if err != nil {
+			return errors.Wrap(err)
+		}
 
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err)
-	}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return errors.Wrap(err)
+		}
 
-	if affected == 0 {
-		return errors.NotFoundf("definition id: %d not found", definitionID)
-	}
+		if affected == 0 {
+			return errors.NotFoundf("definition id: %d not found", definitionID)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func (r *Repo) DeleteDefinitionJobMap(ctx context.Context, definitionID int, mapDescription string) error {
@@ -573,22 +582,7 @@ func (r *Repo) JobDefinition(ctx context.Context, jobID int) ([]DefinitionJob, e
 		    LEFT JOIN definition d ON djm.definition_id = d.id 
 			LEFT JOIN env_data ed on ed.job_id = $1
 			LEFT OUTER JOIN definition_type dt on d.definition_type_id = dt.id
-		WHERE
-			(djm.job_id = $1)
-		OR
-			(djm.cluster_id = ed.cluster_id AND djm.artifact_id IS NULL)
-		OR
-		    (djm.environment_id = ed.environment_id AND djm.artifact_id IS NULL) 
-		OR
-		    (djm.namespace_id = ed.namespace_id AND djm.artifact_id IS NULL)
-		OR
-		    (djm.artifact_id = ed.artifact_id AND djm.environment_id IS NULL AND djm.namespace_id IS NULL AND djm.cluster_id IS NULL)
-		OR
-		    (djm.artifact_id = ed.artifact_id AND djm.cluster_id = ed.cluster_id)
-		OR
-		    (djm.artifact_id = ed.artifact_id AND djm.environment_id = ed.environment_id)
-		OR
-		    (djm.artifact_id = ed.artifact_id AND djm.namespace_id = ed.namespace_id)
+		WHERE`+mapApplies("djm", "job_id")+`
 		ORDER BY
 			djm.stacking_order
 	`, jobID)
@@ -649,22 +643,7 @@ func (r *Repo) ServiceDefinition(ctx context.Context, serviceID int) ([]Definiti
 		    LEFT JOIN definition d ON dsm.definition_id = d.id 
 			LEFT JOIN env_data ed on ed.service_id = $1
 			LEFT OUTER JOIN definition_type dt on d.definition_type_id = dt.id
-		WHERE
-			(dsm.service_id = $1)
-		OR
-			(dsm.cluster_id = ed.cluster_id AND dsm.artifact_id IS NULL)
-		OR
-		    (dsm.environment_id = ed.environment_id AND dsm.artifact_id IS NULL) 
-		OR
-		    (dsm.namespace_id = ed.namespace_id AND dsm.artifact_id IS NULL)
-		OR
-		    (dsm.artifact_id = ed.artifact_id AND dsm.environment_id IS NULL AND dsm.namespace_id IS NULL AND dsm.cluster_id IS NULL)
-		OR
-		    (dsm.artifact_id = ed.artifact_id AND dsm.cluster_id = ed.cluster_id)
-		OR
-		    (dsm.artifact_id = ed.artifact_id AND dsm.environment_id = ed.environment_id)
-		OR
-		    (dsm.artifact_id = ed.artifact_id AND dsm.namespace_id = ed.namespace_id)
+		WHERE`+mapApplies("dsm", "service_id")+`
 		ORDER BY
 			dsm.stacking_order
 	`, serviceID)
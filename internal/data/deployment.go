@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	goErrors "errors"
+	"fmt"
 	"time"
 
 	uuid "github.com/satori/go.uuid"
@@ -18,8 +19,22 @@ const (
 	DeploymentStateQueued    DeploymentState = "queued"
 	DeploymentStateScheduled DeploymentState = "scheduled"
 	DeploymentStateCompleted DeploymentState = "completed"
+	DeploymentStateFailed    DeploymentState = "failed"
+	DeploymentStatePartial   DeploymentState = "partial"
+	DeploymentStateCancelled DeploymentState = "cancelled"
+	DeploymentStateTimedOut  DeploymentState = "timed_out"
 )
 
+// Terminal is true once a deployment can no longer change state
+func (s DeploymentState) Terminal() bool {
+	switch s {
+	case DeploymentStateQueued, DeploymentStateScheduled:
+		return false
+	default:
+		return true
+	}
+}
+
 type Deployment struct {
 	ID            uuid.UUID       `db:"id"`
 	EnvironmentID int             `db:"environment_id"`
@@ -31,6 +46,8 @@ type Deployment struct {
 	PlanLocation  json.Object     `db:"plan_location"`
 	State         DeploymentState `db:"state"`
 	User          string          `db:"user"`
+	RollbackOf    uuid.NullUUID   `db:"rollback_of"`
+	ScheduledAt   sql.NullTime    `db:"scheduled_at"`
 	CreatedAt     sql.NullTime    `db:"created_at"`
 	UpdatedAt     sql.NullTime    `db:"updated_at"`
 }
@@ -52,9 +69,11 @@ func (r *Repo) UpdateDeploymentMessageID(ctx context.Context, id uuid.UUID, mess
 	return nil
 }
 
+// UpdateDeploymentPlanLocation moves a queued deployment to scheduled, a NotFoundError is returned when it's no longer
+// queued (it was cancelled or timed out while it was being scheduled)
 func (r *Repo) UpdateDeploymentPlanLocation(ctx context.Context, id uuid.UUID, location json.Object) error {
-	result, err := r.db.ExecContext(ctx, "update deployment set plan_location = $1, state = $2, updated_at = $3 where id = $4",
-		location, DeploymentStateScheduled, time.Now().UTC(), id)
+	result, err := r.db.ExecContext(ctx, "update deployment set plan_location = $1, state = $2, updated_at = $3, scheduled_at = $3 where id = $4 and state = $5",
+		location, DeploymentStateScheduled, time.Now().UTC(), id, DeploymentStateQueued)
 	if err != nil {
 		return errors.Wrap(err)
 	}
@@ -65,27 +84,59 @@ func (r *Repo) UpdateDeploymentPlanLocation(ctx context.Context, id uuid.UUID, l
 	}
 
 	if affected == 0 {
-		return errors.Wrapf("the following id: %s was not found to update in deployment table", id)
+		return NotFoundErrorf("queued deployment with id: %s not found", id.String())
 	}
 	return nil
 }
 
-func (r *Repo) UpdateDeploymentResult(ctx context.Context, id uuid.UUID) (*Deployment, error) {
+// UpdateDeploymentResult moves a deployment into its final state, a NotFoundError is returned when the deployment
+// does not exist or has already finished (e.g. it was cancelled while the scheduler was still working on it)
+func (r *Repo) UpdateDeploymentResult(ctx context.Context, id uuid.UUID, state DeploymentState) (*Deployment, error) {
 	var deployment Deployment
 
 	row := r.db.QueryRowxContext(ctx, `
-		update deployment set state = $1, updated_at = $2 where id = $3
+		update deployment set state = $1, updated_at = $2 where id = $3 and state in ($4, $5)
 		returning *
-		`, DeploymentStateCompleted, time.Now().UTC(), id)
+		`, state, time.Now().UTC(), id, DeploymentStateQueued, DeploymentStateScheduled)
 
 	err := row.StructScan(&deployment)
 	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, NotFoundErrorf("unfinished deployment with id: %s not found", id.String())
+		}
 		return nil, errors.Wrap(err)
 	}
 
 	return &deployment, nil
 }
 
+// CancelDeployment moves an unfinished deployment to cancelled, returning it along with the state it was cancelled in.
+// The previous state comes from the same statement so a deployment scheduled in the meantime isn't missed. A
+// NotFoundError is returned when the deployment does not exist or has already finished
+func (r *Repo) CancelDeployment(ctx context.Context, id uuid.UUID) (*Deployment, DeploymentState, error) {
+	var cancelled struct {
+		Deployment
+		PreviousState DeploymentState `db:"previous_state"`
+	}
+
+	row := r.db.QueryRowxContext(ctx, `
+		update deployment d set state = $1, updated_at = $2
+		from (select id, state from deployment where id = $3 for update) previous
+		where d.id = previous.id and previous.state in ($4, $5)
+		returning d.*, previous.state as previous_state
+		`, DeploymentStateCancelled, time.Now().UTC(), id, DeploymentStateQueued, DeploymentStateScheduled)
+
+	err := row.StructScan(&cancelled)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, "", NotFoundErrorf("unfinished deployment with id: %s not found", id.String())
+		}
+		return nil, "", errors.Wrap(err)
+	}
+
+	return &cancelled.Deployment, cancelled.PreviousState, nil
+}
+
 func (r *Repo) DeploymentByID(ctx context.Context, id uuid.UUID) (*Deployment, error) {
 	var deployment Deployment
 
@@ -101,12 +152,65 @@ func (r *Repo) DeploymentByID(ctx context.Context, id uuid.UUID) (*Deployment, e
 	return &deployment, nil
 }
 
+// Deployments pages through deployments ordered by (created_at, id), a limit of 0 returns every matching row
+func (r *Repo) Deployments(ctx context.Context, limit int, ascending bool, whereArgs ...WhereArg) ([]Deployment, error) {
+	esql, args := CheckWhereArgs(`
+		select d.*
+		from deployment d
+			join environment e on d.environment_id = e.id
+			join namespace n on d.namespace_id = n.id
+	`, whereArgs)
+
+	if ascending {
+		esql = fmt.Sprintf("%s order by d.created_at asc, d.id asc", esql)
+	} else {
+		esql = fmt.Sprintf("%s order by d.created_at desc, d.id desc", esql)
+	}
+
+	if limit > 0 {
+		esql = fmt.Sprintf("%s limit $%d", esql, len(args)+1)
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryxContext(ctx, esql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var deployments []Deployment
+	for rows.Next() {
+		var deployment Deployment
+		err = rows.StructScan(&deployment)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		deployments = append(deployments, deployment)
+	}
+
+	return deployments, nil
+}
+
+// StuckDeployments returns the unfinished deployments that haven't changed since the supplied time, scheduled ones the
+// scheduler never replied to and queued ones that were never scheduled (their message was dead lettered). A queued
+// deployment's updated_at moves every time its message is received
+func (r *Repo) StuckDeployments(ctx context.Context, before time.Time) ([]Deployment, error) {
+	return r.Deployments(ctx, 0, true,
+		WhereExpr("d.state in (?, ?)", DeploymentStateQueued, DeploymentStateScheduled),
+		WhereLessThan("d.updated_at", before))
+}
+
+// UpdateDeploymentReceiptHandle leaves updated_at alone once a deployment is scheduled, a redelivered message
+// shouldn't reset the clock used to time out deployments the scheduler never replied to
 func (r *Repo) UpdateDeploymentReceiptHandle(ctx context.Context, id uuid.UUID, receiptHandle string) (*Deployment, error) {
 	var deployment Deployment
 	row := r.db.QueryRowxContext(ctx, `
-		update deployment set receipt_handle = $1, updated_at = $2 where id = $3
+		update deployment
+		set receipt_handle = $1,
+		    updated_at = case when state = $4 then updated_at else $2 end
+		where id = $3
 		returning *
-	`, receiptHandle, time.Now().UTC(), id)
+	`, receiptHandle, time.Now().UTC(), id, DeploymentStateScheduled)
 	err := row.StructScan(&deployment)
 	if err != nil {
 		return nil, errors.Wrap(err)
@@ -128,11 +232,11 @@ func (r *Repo) CreateDeployment(ctx context.Context, d *Deployment) error {
 
 	err := r.db.QueryRowxContext(ctx, `
 	
-	insert into deployment(environment_id, namespace_id, req_id, plan_options, plan_location, state, "user", created_at, updated_at) 
-		values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	insert into deployment(environment_id, namespace_id, req_id, plan_options, plan_location, state, "user", rollback_of, created_at, updated_at) 
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		returning (id)
 	
-	`, d.EnvironmentID, d.NamespaceID, d.ReqID, d.PlanOptions, d.PlanLocation, DeploymentStateQueued, d.User, d.CreatedAt, d.UpdatedAt).
+	`, d.EnvironmentID, d.NamespaceID, d.ReqID, d.PlanOptions, d.PlanLocation, DeploymentStateQueued, d.User, d.RollbackOf, d.CreatedAt, d.UpdatedAt).
 		Scan(&d.ID)
 
 	if err != nil {
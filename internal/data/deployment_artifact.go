@@ -0,0 +1,153 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/unanet/go/pkg/errors"
+)
+
+type DeploymentArtifact struct {
+	ID               int            `db:"id"`
+	DeploymentID     uuid.UUID      `db:"deployment_id"`
+	EnvironmentID    int            `db:"environment_id"`
+	NamespaceID      int            `db:"namespace_id"`
+	ServiceID        sql.NullInt32  `db:"service_id"`
+	JobID            sql.NullInt32  `db:"job_id"`
+	Name             string         `db:"name"`
+	FromVersion      sql.NullString `db:"from_version"`
+	ToVersion        string         `db:"to_version"`
+	RequestedVersion sql.NullString `db:"requested_version"`
+	DeployedVersion  sql.NullString `db:"deployed_version"`
+	Result           string         `db:"result"`
+	ExitCode         int            `db:"exit_code"`
+	User             string         `db:"user"`
+	CreatedAt        sql.NullTime   `db:"created_at"`
+}
+
+type DeploymentArtifacts []DeploymentArtifact
+
+// RecordDeployResult records the outcome of deploying a service/job along with the version it replaced.
+// The deployed_version on the service/job table is only moved forward when deployed is true, a result that was
+// already recorded for the deployment is left as is
+func (r *Repo) RecordDeployResult(ctx context.Context, artifact *DeploymentArtifact, deployed bool) error {
+	var tableName string
+	var id int
+	switch {
+	case artifact.ServiceID.Valid:
+		tableName, id = "service", int(artifact.ServiceID.Int32)
+	case artifact.JobID.Valid:
+		tableName, id = "job", int(artifact.JobID.Int32)
+	default:
+		return errors.Wrapf("deployment artifact requires either a service or a job id")
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	err = tx.QueryRowxContext(ctx, fmt.Sprintf("select deployed_version from %s where id = $1 for update", tableName), id).
+		Scan(&artifact.FromVersion)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return errors.WrapTx(tx, errors.Wrapf("the following id: %d was not found to update in %s table", id, tableName))
+		}
+		return errors.WrapTx(tx, err)
+	}
+
+	// a redelivered result was already recorded by the first delivery, so there is nothing left to do
+	now := time.Now().UTC()
+	err = tx.QueryRowxContext(ctx, `
+		insert into deployment_artifact(deployment_id, service_id, job_id, from_version, to_version, requested_version,
+		                                deployed_version, result, exit_code, created_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		on conflict do nothing
+		returning id, created_at
+	`, artifact.DeploymentID, artifact.ServiceID, artifact.JobID, artifact.FromVersion, artifact.ToVersion,
+		artifact.RequestedVersion, artifact.DeployedVersion, artifact.Result, artifact.ExitCode, now).
+		Scan(&artifact.ID, &artifact.CreatedAt)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			_ = tx.Rollback()
+			return nil
+		}
+		return errors.WrapTx(tx, err)
+	}
+
+	if deployed {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			update %s
+			set deployed_version = $1,
+			    updated_at = $2
+			where id = $3
+		`, tableName), artifact.ToVersion, now, id)
+		if err != nil {
+			return errors.WrapTx(tx, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+	return nil
+}
+
+func (r *Repo) DeploymentArtifactsByDeploymentID(ctx context.Context, deploymentID uuid.UUID) (DeploymentArtifacts, error) {
+	return r.DeploymentArtifacts(ctx, 0, 0, Where("da.deployment_id", deploymentID))
+}
+
+// DeploymentArtifacts returns the recorded version transitions newest first, a limit of 0 returns every matching row
+func (r *Repo) DeploymentArtifacts(ctx context.Context, limit int, offset int, whereArgs ...WhereArg) (DeploymentArtifacts, error) {
+	esql, args := CheckWhereArgs(`
+		select da.id,
+		       da.deployment_id,
+		       d.environment_id,
+		       d.namespace_id,
+		       da.service_id,
+		       da.job_id,
+		       COALESCE(s.name, j.name) as name,
+		       da.from_version,
+		       da.to_version,
+		       da.requested_version,
+		       da.deployed_version,
+		       da.result,
+		       da.exit_code,
+		       d."user",
+		       da.created_at
+		from deployment_artifact da
+			join deployment d on da.deployment_id = d.id
+			left join service s on da.service_id = s.id
+			left join job j on da.job_id = j.id
+	`, whereArgs)
+
+	esql = fmt.Sprintf("%s order by da.created_at desc, da.id desc", esql)
+	if limit > 0 {
+		esql = fmt.Sprintf("%s limit $%d offset $%d", esql, len(args)+1, len(args)+2)
+		args = append(args, limit, offset)
+	}
+
+	rows, err := r.db.QueryxContext(ctx, esql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var artifacts DeploymentArtifacts
+	for rows.Next() {
+		var artifact DeploymentArtifact
+		err = rows.StructScan(&artifact)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts, nil
+}
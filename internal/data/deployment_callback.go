@@ -0,0 +1,239 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+)
+
+type DeploymentCallbackState string
+
+const (
+	DeploymentCallbackStatePending   DeploymentCallbackState = "pending"
+	DeploymentCallbackStateDelivered DeploymentCallbackState = "delivered"
+	DeploymentCallbackStateFailed    DeploymentCallbackState = "failed"
+)
+
+type DeploymentCallback struct {
+	ID             int                     `db:"id"`
+	DeploymentID   uuid.NullUUID           `db:"deployment_id"`
+	SubscriptionID sql.NullInt32           `db:"subscription_id"`
+	Event          sql.NullString          `db:"event"`
+	URL            string                  `db:"url"`
+	Body           json.Object             `db:"body"`
+	State          DeploymentCallbackState `db:"state"`
+	Attempts       int                     `db:"attempts"`
+	NextAttemptAt  sql.NullTime            `db:"next_attempt_at"`
+	LastStatusCode sql.NullInt32           `db:"last_status_code"`
+	LastError      sql.NullString          `db:"last_error"`
+	DeliveredAt    sql.NullTime            `db:"delivered_at"`
+	CreatedAt      sql.NullTime            `db:"created_at"`
+	UpdatedAt      sql.NullTime            `db:"updated_at"`
+}
+
+type DeploymentCallbacks []DeploymentCallback
+
+type DeploymentCallbackAttempt struct {
+	ID                   int            `db:"id"`
+	DeploymentCallbackID int            `db:"deployment_callback_id"`
+	StatusCode           sql.NullInt32  `db:"status_code"`
+	Error                sql.NullString `db:"error"`
+	ElapsedMs            int            `db:"elapsed_ms"`
+	CreatedAt            sql.NullTime   `db:"created_at"`
+}
+
+type DeploymentCallbackAttempts []DeploymentCallbackAttempt
+
+// CreateDeploymentCallback adds a callback to the outbox, it's picked up on the next dispatch. Subscription callbacks
+// aren't always for a deployment so the deployment id can be left out
+func (r *Repo) CreateDeploymentCallback(ctx context.Context, c *DeploymentCallback) error {
+	now := time.Now().UTC()
+	c.State = DeploymentCallbackStatePending
+	c.NextAttemptAt = sql.NullTime{Time: now, Valid: true}
+	c.CreatedAt = sql.NullTime{Time: now, Valid: true}
+	c.UpdatedAt = sql.NullTime{Time: now, Valid: true}
+	err := r.db.QueryRowxContext(ctx, `
+		insert into deployment_callback(deployment_id, subscription_id, event, url, body, state, next_attempt_at, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $7, $7)
+		returning id
+	`, c.DeploymentID, c.SubscriptionID, c.Event, c.URL, c.Body, c.State, now).
+		Scan(&c.ID)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	return nil
+}
+
+// DueDeploymentCallbacks claims pending callbacks that are due by pushing their next attempt out by the lease, so
+// another dispatcher doesn't pick them up while they're being delivered. Like the queue groups, only the oldest pending
+// callback for a deployment and url is returned so the receiver sees them in order
+func (r *Repo) DueDeploymentCallbacks(ctx context.Context, limit int, lease time.Duration) (DeploymentCallbacks, error) {
+	now := time.Now().UTC()
+	rows, err := r.db.QueryxContext(ctx, `
+		update deployment_callback
+		set next_attempt_at = $2
+		where id in (
+			select c.id
+			from deployment_callback c
+			where c.state = $3
+			  and c.next_attempt_at <= $1
+			  and not exists (
+				select 1 from deployment_callback o
+				where o.deployment_id is not distinct from c.deployment_id and o.url = c.url and o.state = $3 and o.id < c.id
+			  )
+			order by c.id
+			limit $4
+			for update skip locked
+		)
+		returning *
+	`, now, now.Add(lease), DeploymentCallbackStatePending, limit)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var callbacks DeploymentCallbacks
+	for rows.Next() {
+		var c DeploymentCallback
+		err = rows.StructScan(&c)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		callbacks = append(callbacks, c)
+	}
+
+	return callbacks, rows.Err()
+}
+
+// RecordDeploymentCallbackAttempt logs the attempt and saves the callback's new state, attempts and next attempt
+func (r *Repo) RecordDeploymentCallbackAttempt(ctx context.Context, c *DeploymentCallback, attempt *DeploymentCallbackAttempt) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	now := time.Now().UTC()
+	attempt.DeploymentCallbackID = c.ID
+	attempt.CreatedAt = sql.NullTime{Time: now, Valid: true}
+	err = tx.QueryRowxContext(ctx, `
+		insert into deployment_callback_attempt(deployment_callback_id, status_code, error, elapsed_ms, created_at)
+		values ($1, $2, $3, $4, $5)
+		returning id
+	`, attempt.DeploymentCallbackID, attempt.StatusCode, attempt.Error, attempt.ElapsedMs, now).
+		Scan(&attempt.ID)
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+
+	c.UpdatedAt = sql.NullTime{Time: now, Valid: true}
+	_, err = tx.ExecContext(ctx, `
+		update deployment_callback
+		set state = $1,
+		    attempts = $2,
+		    next_attempt_at = $3,
+		    last_status_code = $4,
+		    last_error = $5,
+		    delivered_at = $6,
+		    updated_at = $7
+		where id = $8
+	`, c.State, c.Attempts, c.NextAttemptAt, c.LastStatusCode, c.LastError, c.DeliveredAt, now, c.ID)
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+	return nil
+}
+
+func (r *Repo) DeploymentCallbackByID(ctx context.Context, id int) (*DeploymentCallback, error) {
+	var c DeploymentCallback
+	row := r.db.QueryRowxContext(ctx, "select * from deployment_callback where id = $1", id)
+	err := row.StructScan(&c)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, NotFoundErrorf("deployment callback with id: %d, not found", id)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	return &c, nil
+}
+
+func (r *Repo) DeploymentCallbacksByDeploymentID(ctx context.Context, deploymentID uuid.UUID) (DeploymentCallbacks, error) {
+	rows, err := r.db.QueryxContext(ctx, "select * from deployment_callback where deployment_id = $1 order by id", deploymentID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var callbacks DeploymentCallbacks
+	for rows.Next() {
+		var c DeploymentCallback
+		err = rows.StructScan(&c)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		callbacks = append(callbacks, c)
+	}
+
+	return callbacks, nil
+}
+
+func (r *Repo) DeploymentCallbackAttemptsByDeploymentID(ctx context.Context, deploymentID uuid.UUID) (DeploymentCallbackAttempts, error) {
+	rows, err := r.db.QueryxContext(ctx, `
+		select a.*
+		from deployment_callback_attempt a
+		    join deployment_callback c on a.deployment_callback_id = c.id
+		where c.deployment_id = $1
+		order by a.id
+	`, deploymentID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var attempts DeploymentCallbackAttempts
+	for rows.Next() {
+		var a DeploymentCallbackAttempt
+		err = rows.StructScan(&a)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		attempts = append(attempts, a)
+	}
+
+	return attempts, nil
+}
+
+// RedeliverDeploymentCallback puts a callback back in the outbox with a fresh set of attempts, the attempt log is kept
+func (r *Repo) RedeliverDeploymentCallback(ctx context.Context, id int) (*DeploymentCallback, error) {
+	now := time.Now().UTC()
+	var c DeploymentCallback
+	row := r.db.QueryRowxContext(ctx, `
+		update deployment_callback
+		set state = $1,
+		    attempts = 0,
+		    next_attempt_at = $2,
+		    updated_at = $2
+		where id = $3
+		returning *
+	`, DeploymentCallbackStatePending, now, id)
+	err := row.StructScan(&c)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, NotFoundErrorf("deployment callback with id: %d, not found", id)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	return &c, nil
+}
@@ -140,7 +140,7 @@ func (r *Repo) UpdateFinishedJobs(ctx context.Context) error {
 		where state = 'running' and
 		      (select count(*) from deployment_cron_job as dcj
 		    		left join deployment d on dcj.deployment_id = d.id
-		    		where d.state != 'completed' and dcj.deployment_cron_id = deployment_cron.id) = 0 
+		    		where d.state in ('queued', 'scheduled') and dcj.deployment_cron_id = deployment_cron.id) = 0
 	`, now)
 	if err != nil {
 		return errors.Wrap(err)
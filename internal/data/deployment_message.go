@@ -0,0 +1,79 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/unanet/go/pkg/errors"
+)
+
+const (
+	DeploymentMessageSourcePlan     = "plan"
+	DeploymentMessageSourceCallback = "callback"
+	DeploymentMessageSourceCancel   = "cancel"
+	DeploymentMessageSourceTimeout  = "timeout"
+)
+
+type DeploymentMessage struct {
+	ID           int          `db:"id"`
+	DeploymentID uuid.UUID    `db:"deployment_id"`
+	Source       string       `db:"source"`
+	Message      string       `db:"message"`
+	CreatedAt    sql.NullTime `db:"created_at"`
+}
+
+type DeploymentMessages []DeploymentMessage
+
+// CreateDeploymentMessages records the messages for a deployment, a message it already has from the source is skipped
+// so a redelivered update can't duplicate them
+func (r *Repo) CreateDeploymentMessages(ctx context.Context, deploymentID uuid.UUID, source string, messages []string) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	now := time.Now().UTC()
+	for _, x := range messages {
+		_, err = tx.ExecContext(ctx, `
+			insert into deployment_message(deployment_id, source, message, created_at)
+			values ($1, $2, $3, $4)
+			on conflict (deployment_id, source, md5(message)) do nothing
+		`, deploymentID, source, x, now)
+		if err != nil {
+			return errors.WrapTx(tx, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+	return nil
+}
+
+func (r *Repo) DeploymentMessagesByDeploymentID(ctx context.Context, deploymentID uuid.UUID) (DeploymentMessages, error) {
+	rows, err := r.db.QueryxContext(ctx, "select * from deployment_message where deployment_id = $1 order by id", deploymentID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var messages DeploymentMessages
+	for rows.Next() {
+		var message DeploymentMessage
+		err = rows.StructScan(&message)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
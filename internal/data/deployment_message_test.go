@@ -0,0 +1,40 @@
+// +build local
+
+package data_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unanet/go/pkg/json"
+
+	"github.com/unanet/eve/internal/data"
+)
+
+func TestRepo_CreateDeploymentMessages_Redelivered(t *testing.T) {
+	ctx := context.Background()
+	repo := getRepo(t)
+
+	namespaces, err := repo.Namespaces(ctx)
+	require.NoError(t, err)
+	if len(namespaces) == 0 {
+		t.Skip("no namespace to deploy to")
+	}
+	deployment := &data.Deployment{
+		EnvironmentID: namespaces[0].EnvironmentID,
+		NamespaceID:   namespaces[0].ID,
+		PlanOptions:   json.Object(`{}`),
+		State:         data.DeploymentStateScheduled,
+		User:          "message-test",
+	}
+	require.NoError(t, repo.CreateDeployment(ctx, deployment))
+
+	messages := []string{"artifact failed to deploy", "artifact deployed"}
+	require.NoError(t, repo.CreateDeploymentMessages(ctx, deployment.ID, data.DeploymentMessageSourcePlan, messages))
+	require.NoError(t, repo.CreateDeploymentMessages(ctx, deployment.ID, data.DeploymentMessageSourcePlan, messages))
+
+	stored, err := repo.DeploymentMessagesByDeploymentID(ctx, deployment.ID)
+	require.NoError(t, err)
+	require.Len(t, stored, len(messages))
+}
@@ -41,28 +41,6 @@ type Job struct {
 	Name            string         `db:"name"`
 }
 
-func (r *Repo) UpdateDeployedJobVersion(ctx context.Context, id int, version string) error {
-	result, err := r.db.ExecContext(ctx, `
-		update job
-		set deployed_version = $1, 
-		    updated_at = $2 
-		where id = $3
-	`, version, time.Now().UTC(), id)
-	if err != nil {
-		return errors.Wrap(err)
-	}
-
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err)
-	}
-
-	if affected == 0 {
-		return errors.Wrapf("the following id: %d was not found to update in job table", id)
-	}
-	return nil
-}
-
 func (r *Repo) DeployedJobsByNamespaceID(ctx context.Context, namespaceID int) (DeployJobs, error) {
 	rows, err := r.db.QueryxContext(ctx, `
 		select j.id as job_id,
@@ -0,0 +1,150 @@
+package data
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/unanet/go/pkg/errors"
+)
+
+// MapScope is what a metadata or definition map row is keyed on, zero values aren't set
+type MapScope struct {
+	ArtifactID    int32
+	EnvironmentID int32
+	NamespaceID   int32
+	ClusterID     int32
+	ServiceID     int32
+	JobID         int32
+}
+
+// mapApplies is the predicate for a metadata or definition map row (alias m) applying to the service or job in the
+// env_data row (alias ed), target is "service_id" or "job_id". It's shared by the merge lookups and the map previews
+// so the two can't drift
+func mapApplies(m, target string) string {
+	return fmt.Sprintf(`
+			(%[1]s.%[2]s = ed.%[2]s)
+		OR
+			(%[1]s.cluster_id = ed.cluster_id AND %[1]s.artifact_id IS NULL)
+		OR
+		    (%[1]s.environment_id = ed.environment_id AND %[1]s.artifact_id IS NULL)
+		OR
+		    (%[1]s.namespace_id = ed.namespace_id AND %[1]s.artifact_id IS NULL)
+		OR
+		    (%[1]s.artifact_id = ed.artifact_id AND %[1]s.environment_id IS NULL AND %[1]s.namespace_id IS NULL AND %[1]s.cluster_id IS NULL)
+		OR
+		    (%[1]s.artifact_id = ed.artifact_id AND %[1]s.cluster_id = ed.cluster_id)
+		OR
+		    (%[1]s.artifact_id = ed.artifact_id AND %[1]s.environment_id = ed.environment_id)
+		OR
+		    (%[1]s.artifact_id = ed.artifact_id AND %[1]s.namespace_id = ed.namespace_id)
+		`, m, target)
+}
+
+// scopeMap selects the scope as if it were a map row, so mapApplies can be used to find what it applies to
+const scopeMap = `
+		scope_map AS (
+			select nullif($1::int, 0) as artifact_id,
+			       nullif($2::int, 0) as environment_id,
+			       nullif($3::int, 0) as namespace_id,
+			       nullif($4::int, 0) as cluster_id,
+			       nullif($5::int, 0) as target_id
+		)`
+
+// ServicesInMapScope returns the services a metadata or definition service map with the scope applies to
+func (r *Repo) ServicesInMapScope(ctx context.Context, scope MapScope) ([]Service, error) {
+	rows, err := r.db.QueryxContext(ctx, `
+		WITH env_data AS (
+			select s.id as service_id,
+			       n.environment_id,
+			       s.namespace_id,
+			       s.artifact_id,
+			       n.cluster_id
+			from service s
+			    left join namespace n on s.namespace_id = n.id
+		),`+scopeMap+`
+
+		select s.id,
+		       s.namespace_id,
+		       s.artifact_id,
+		       s.override_version,
+		       s.deployed_version,
+		       s.created_at,
+		       s.updated_at,
+		       s.name,
+		       s.count,
+		       n.name as namespace_name,
+		       a.name as artifact_name
+		from service s
+		    left join namespace n on s.namespace_id = n.id
+			left join artifact a on s.artifact_id = a.id
+			join env_data ed on ed.service_id = s.id
+			cross join (select artifact_id, environment_id, namespace_id, cluster_id, target_id as service_id from scope_map) sm
+		where `+mapApplies("sm", "service_id")+`
+		order by s.name
+	`, scope.ArtifactID, scope.EnvironmentID, scope.NamespaceID, scope.ClusterID, scope.ServiceID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var services []Service
+	for rows.Next() {
+		var service Service
+		err = rows.StructScan(&service)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		services = append(services, service)
+	}
+
+	return services, nil
+}
+
+// JobsInMapScope returns the jobs a metadata or definition job map with the scope applies to
+func (r *Repo) JobsInMapScope(ctx context.Context, scope MapScope) ([]Job, error) {
+	rows, err := r.db.QueryxContext(ctx, `
+		WITH env_data AS (
+			select j.id as job_id,
+			       n.environment_id,
+			       j.namespace_id,
+			       j.artifact_id,
+			       n.cluster_id
+			from job j
+			    left join namespace n on j.namespace_id = n.id
+		),`+scopeMap+`
+
+		select j.id,
+		       j.namespace_id,
+		       j.artifact_id,
+		       j.override_version,
+		       j.deployed_version,
+		       j.created_at,
+		       j.updated_at,
+		       j.name,
+		       n.name as namespace_name,
+		       a.name as artifact_name
+		from job j
+		    left join namespace n on j.namespace_id = n.id
+			left join artifact a on j.artifact_id = a.id
+			join env_data ed on ed.job_id = j.id
+			cross join (select artifact_id, environment_id, namespace_id, cluster_id, target_id as job_id from scope_map) sm
+		where `+mapApplies("sm", "job_id")+`
+		order by j.name
+	`, scope.ArtifactID, scope.EnvironmentID, scope.NamespaceID, scope.ClusterID, scope.JobID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var job Job
+		err = rows.StructScan(&job)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,54 @@
+// +build local
+
+package data_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unanet/eve/internal/data"
+)
+
+func TestRepo_ServicesInMapScope(t *testing.T) {
+	ctx := context.Background()
+	repo := getRepo(t)
+
+	services, err := repo.Services(ctx)
+	require.NoError(t, err)
+	if len(services) == 0 {
+		t.Skip("no service to scope")
+	}
+	service := services[0]
+	namespace, err := repo.NamespaceByID(ctx, service.NamespaceID)
+	require.NoError(t, err)
+
+	const missing = math.MaxInt32
+	tests := []struct {
+		name  string
+		scope data.MapScope
+		want  bool
+	}{
+		{"cluster+environment matches on the cluster", data.MapScope{ClusterID: int32(namespace.ClusterID), EnvironmentID: missing}, true},
+		{"cluster+environment matches on the environment", data.MapScope{ClusterID: missing, EnvironmentID: int32(namespace.EnvironmentID)}, true},
+		{"artifact+cluster+environment matches on the environment", data.MapScope{ArtifactID: int32(service.ArtifactID), ClusterID: missing, EnvironmentID: int32(namespace.EnvironmentID)}, true},
+		{"artifact+namespace needs both", data.MapScope{ArtifactID: int32(service.ArtifactID), NamespaceID: missing}, false},
+		{"artifact", data.MapScope{ArtifactID: int32(service.ArtifactID)}, true},
+		{"service", data.MapScope{ServiceID: int32(service.ID)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scoped, err := repo.ServicesInMapScope(ctx, tt.scope)
+			require.NoError(t, err)
+			var found bool
+			for _, x := range scoped {
+				if x.ID == service.ID {
+					found = true
+				}
+			}
+			require.Equal(t, tt.want, found)
+		})
+	}
+}
@@ -3,11 +3,15 @@ package data
 import (
 	"context"
 	"database/sql"
+	goErrors "errors"
+	"fmt"
+
 	"github.com/unanet/go/pkg/errors"
 	"github.com/unanet/go/pkg/json"
 )
 
 type MetadataHistory struct {
+	ID          int          `db:"id"`
 	MetadataId  int          `db:"metadata_id"`
 	Description string       `db:"description"`
 	Value       json.Object  `db:"value"`
@@ -18,8 +22,14 @@ type MetadataHistory struct {
 }
 
 func (r *Repo) MetadataHistory(ctx context.Context) ([]MetadataHistory, error) {
-	rows, err := r.db.QueryxContext(ctx, `
+	return r.MetadataRevisions(ctx, 100, 0) // Arbitrary value to limit by
+}
+
+func (r *Repo) MetadataRevision(ctx context.Context, metadataID int, revision int) (*MetadataHistory, error) {
+	var m MetadataHistory
+	err := r.db.QueryRowxContext(ctx, `
 		SELECT 
+			id,
 			metadata_id,
 			description,
 			value,
@@ -28,8 +38,40 @@ func (r *Repo) MetadataHistory(ctx context.Context) ([]MetadataHistory, error) {
 			deleted,
 			deleted_by
 		FROM metadata_history 
-		LIMIT 100`) // Arbitrary value to limit by
+		WHERE metadata_id = $1 AND id = $2
+	`, metadataID, revision).StructScan(&m)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, NotFoundErrorf("metadata with id: %d has no revision: %d", metadataID, revision)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	return &m, nil
+}
+
+// MetadataRevisions returns metadata history newest first, a limit of 0 returns every matching revision
+func (r *Repo) MetadataRevisions(ctx context.Context, limit int, offset int, whereArgs ...WhereArg) ([]MetadataHistory, error) {
+	esql, args := CheckWhereArgs(`
+		SELECT 
+			id,
+			metadata_id,
+			description,
+			value,
+			created,
+			created_by,
+			deleted,
+			deleted_by
+		FROM metadata_history 
+	`, whereArgs)
+
+	esql = fmt.Sprintf("%s ORDER BY id DESC", esql)
+	if limit > 0 {
+		esql = fmt.Sprintf("%s LIMIT $%d OFFSET $%d", esql, len(args)+1, len(args)+2)
+		args = append(args, limit, offset)
+	}
 
+	rows, err := r.db.QueryxContext(ctx, esql, args...)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
@@ -6,16 +6,18 @@ import (
 	goErrors "errors"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/unanet/go/pkg/errors"
 	"github.com/unanet/go/pkg/json"
 )
 
 type Metadata struct {
-	ID          int          `db:"id"`
-	Description string       `db:"description"`
-	Value       json.Object  `db:"value"`
-	CreatedAt   sql.NullTime `db:"created_at"`
-	UpdatedAt   sql.NullTime `db:"updated_at"`
+	ID            int          `db:"id"`
+	Description   string       `db:"description"`
+	Value         json.Object  `db:"value"`
+	EncryptedKeys json.Object  `db:"encrypted_keys"`
+	CreatedAt     sql.NullTime `db:"created_at"`
+	UpdatedAt     sql.NullTime `db:"updated_at"`
 }
 
 type MetadataServiceMap struct {
@@ -52,6 +54,7 @@ type MetadataService struct {
 	MapEnvironmentID    sql.NullInt32 `db:"map_environment_id"`
 	MapArtifactID       sql.NullInt32 `db:"map_artifact_id"`
 	MapNamespaceID      sql.NullInt32 `db:"map_namespace_id"`
+	MapClusterID        sql.NullInt32 `db:"map_cluster_id"`
 	MapServiceID        sql.NullInt32 `db:"map_service_id"`
 	StackingOrder       int           `db:"stacking_order"`
 	CreatedAt           sql.NullTime  `db:"created_at"`
@@ -66,6 +69,7 @@ type MetadataJob struct {
 	MapEnvironmentId    sql.NullInt32 `db:"map_environment_id"`
 	MapArtifactId       sql.NullInt32 `db:"map_artifact_id"`
 	MapNamespaceId      sql.NullInt32 `db:"map_namespace_id"`
+	MapClusterId        sql.NullInt32 `db:"map_cluster_id"`
 	MapJobId            sql.NullInt32 `db:"map_job_id"`
 	StackingOrder       int           `db:"stacking_order"`
 	CreatedAt           sql.NullTime  `db:"created_at"`
@@ -83,14 +87,16 @@ func (r *Repo) UpsertMergeMetadata(ctx context.Context, m *Metadata) error {
 		Valid: true,
 	}
 
-	err := r.db.QueryRowxContext(ctx, `
-	INSERT INTO metadata(description, value, created_at, updated_at)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (description)
-		DO UPDATE SET value = metadata.value || $2, updated_at = $4
-		RETURNING id, value, created_at
-	`, m.Description, m.Value, m.CreatedAt, m.UpdatedAt).
-		StructScan(m)
+	err := r.asUser(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowxContext(ctx, `
+		INSERT INTO metadata(description, value, encrypted_keys, created_at, updated_at)
+			VALUES ($1, $2, $5, $3, $4)
+			ON CONFLICT (description)
+			DO UPDATE SET value = metadata.value || $2, encrypted_keys = $5, updated_at = $4
+			RETURNING id, value, encrypted_keys, created_at
+		`, m.Description, m.Value, m.CreatedAt, m.UpdatedAt, m.EncryptedKeys).
+			StructScan(m)
+	})
 
 	if err != nil {
 		return errors.Wrap(err)
@@ -110,16 +116,18 @@ func (r *Repo) UpsertMetadata(ctx context.Context, m *Metadata) error {
 		Valid: true,
 	}
 
-	err := r.db.QueryRowxContext(ctx, `
-	
-	INSERT INTO metadata(description, value, created_at, updated_at)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (description)
-		DO UPDATE SET value = $2, updated_at = $4
-		RETURNING id, created_at
-	
-	`, m.Description, m.Value, m.CreatedAt, m.UpdatedAt).
-		StructScan(m)
+	err := r.asUser(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowxContext(ctx, `
+		
+		INSERT INTO metadata(description, value, encrypted_keys, created_at, updated_at)
+			VALUES ($1, $2, $5, $3, $4)
+			ON CONFLICT (description)
+			DO UPDATE SET value = $2, encrypted_keys = $5, updated_at = $4
+			RETURNING id, created_at
+		
+		`, m.Description, m.Value, m.CreatedAt, m.UpdatedAt, m.EncryptedKeys).
+			StructScan(m)
+	})
 
 	if err != nil {
 		return errors.Wrap(err)
@@ -193,6 +201,7 @@ func (r *Repo) GetMetadata(ctx context.Context, metadataID int) (*Metadata, erro
 		select id, 
 		       description, 
 		       value, 
+		       encrypted_keys,
 		       created_at, 
 		       updated_at
 		from metadata
@@ -216,6 +225,7 @@ func (r *Repo) GetMetadataByDescription(ctx context.Context, description string)
 		select id, 
 		       description, 
 		       value, 
+		       encrypted_keys,
 		       created_at, 
 		       updated_at
 		from metadata
@@ -237,6 +247,7 @@ func (r *Repo) Metadata(ctx context.Context) ([]Metadata, error) {
 		select id, 
 		       description, 
 		       value, 
+		       encrypted_keys,
 		       created_at, 
 		       updated_at 
 		from metadata
@@ -265,10 +276,12 @@ func (r *Repo) Metadata(ctx context.Context) ([]Metadata, error) {
 
 func (r *Repo) DeleteMetadataKey(ctx context.Context, metadataID int, key string) (*Metadata, error) {
 	var metadata Metadata
-	err := r.db.QueryRowxContext(ctx, `
-		UPDATE metadata SET value = metadata.value - $1 WHERE id = $2
-		RETURNING id, value, description, created_at, updated_at
-	`, key, metadataID).StructScan(&metadata)
+	err := r.asUser(ctx, func(tx *sqlx.Tx) error {
+		return tx.QueryRowxContext(ctx, `
+			UPDATE metadata SET value = metadata.value - $1, encrypted_keys = metadata.encrypted_keys - $1 WHERE id = $2
+			RETURNING id, value, encrypted_keys, description, created_at, updated_at
+		`, key, metadataID).StructScan(&metadata)
+	})
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
@@ -277,23 +290,25 @@ func (r *Repo) DeleteMetadataKey(ctx context.Context, metadataID int, key string
 }
 
 func (r *Repo) DeleteMetadata(ctx context.Context, metadataID int) error {
-	result, err := r.db.ExecContext(ctx, `
-		DELETE FROM metadata WHERE id = $1
-	`, metadataID)
-	if err != nil {
-		return errors.Wrap(err)
-	}
+	return r.asUser(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM metadata WHERE id = $1
+		`, metadataID)
+		if err != nil {
+			return errors.Wrap(err)
+		}
 
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err)
-	}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return errors.Wrap(err)
+		}
 
-	if affected == 0 {
-		return errors.NotFoundf("metadata id: %d not found", metadataID)
-	}
+		if affected == 0 {
+			return errors.NotFoundf("metadata id: %d not found", metadataID)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func (r *Repo) DeleteMetadataJobMap(ctx context.Context, metadataID int, mapDescription string) error {
@@ -542,6 +557,7 @@ func (r *Repo) JobMetadata(ctx context.Context, jobID int) ([]MetadataJob, error
 		       mjm.environment_id as map_environment_id,
 		       mjm.artifact_id as map_artifact_id,
 		       mjm.namespace_id as map_namespace_id,
+		       mjm.cluster_id as map_cluster_id,
 		       mjm.job_id as map_job_id,
 		       mjm.stacking_order as stacking_order,
 		       m.created_at,
@@ -549,22 +565,7 @@ func (r *Repo) JobMetadata(ctx context.Context, jobID int) ([]MetadataJob, error
 		FROM metadata_job_map mjm 
 		    LEFT JOIN metadata m ON mjm.metadata_id = m.id 
 			LEFT JOIN env_data ed on ed.job_id = $1
-		WHERE
-			(mjm.job_id = $1)
-		OR
-			(mjm.cluster_id = ed.cluster_id AND mjm.artifact_id IS NULL)
-		OR
-		    (mjm.environment_id = ed.environment_id AND mjm.artifact_id IS NULL) 
-		OR
-		    (mjm.namespace_id = ed.namespace_id AND mjm.artifact_id IS NULL)
-		OR
-		    (mjm.artifact_id = ed.artifact_id AND mjm.environment_id IS NULL AND mjm.namespace_id IS NULL AND mjm.cluster_id IS NULL)
-		OR
-		    (mjm.artifact_id = ed.artifact_id AND mjm.cluster_id = ed.cluster_id)
-		OR
-		    (mjm.artifact_id = ed.artifact_id AND mjm.environment_id = ed.environment_id)
-		OR
-		    (mjm.artifact_id = ed.artifact_id AND mjm.namespace_id = ed.namespace_id)
+		WHERE`+mapApplies("mjm", "job_id")+`
 		ORDER BY
 			mjm.stacking_order
 	`, jobID)
@@ -610,6 +611,7 @@ func (r *Repo) ServiceMetadata(ctx context.Context, serviceID int) ([]MetadataSe
 		       msm.environment_id as map_environment_id,
 		       msm.artifact_id as map_artifact_id,
 		       msm.namespace_id as map_namespace_id,
+		       msm.cluster_id as map_cluster_id,
 		       msm.service_id as map_service_id,
 		       msm.stacking_order as stacking_order,
 		       m.created_at,
@@ -617,22 +619,7 @@ func (r *Repo) ServiceMetadata(ctx context.Context, serviceID int) ([]MetadataSe
 		FROM metadata_service_map msm 
 		    LEFT JOIN metadata m ON msm.metadata_id = m.id 
 			LEFT JOIN env_data ed on ed.service_id = $1
-		WHERE
-			(msm.service_id = $1)
-		OR
-			(msm.cluster_id = ed.cluster_id AND msm.artifact_id IS NULL)
-		OR
-		    (msm.environment_id = ed.environment_id AND msm.artifact_id IS NULL) 
-		OR
-		    (msm.namespace_id = ed.namespace_id AND msm.artifact_id IS NULL)
-		OR
-		    (msm.artifact_id = ed.artifact_id AND msm.environment_id IS NULL AND msm.namespace_id IS NULL AND msm.cluster_id IS NULL)
-		OR
-		    (msm.artifact_id = ed.artifact_id AND msm.cluster_id = ed.cluster_id)
-		OR
-		    (msm.artifact_id = ed.artifact_id AND msm.environment_id = ed.environment_id)
-		OR
-		    (msm.artifact_id = ed.artifact_id AND msm.namespace_id = ed.namespace_id)
+		WHERE`+mapApplies("msm", "service_id")+`
 		ORDER BY
 			msm.stacking_order
 	`, serviceID)
@@ -734,3 +721,111 @@ func (r *Repo) CreateMetadataServiceMap(ctx context.Context, model *MetadataServ
 
 	return nil
 }
+
+// MetadataWithEncryptedHistory returns the ids of the metadata that have a history value with a top level key
+// encrypted under marker, e.g. a key that was encrypted and has since been removed
+func (r *Repo) MetadataWithEncryptedHistory(ctx context.Context, marker string) ([]int, error) {
+	rows, err := r.db.QueryxContext(ctx, `
+		select distinct h.metadata_id
+		from metadata_history h
+			cross join jsonb_each(h.value) e
+		where jsonb_typeof(e.value) = 'object' and e.value ? $1
+	`, marker)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, errors.Wrap(err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// RotateMetadata rewrites the metadata's value and every one of its history values with rotate, all in one
+// transaction so a row is never left half rotated. eve.rotating is set so the update trigger doesn't record the
+// rotation as a new revision
+func (r *Repo) RotateMetadata(ctx context.Context, metadataID int, rotate func(value json.Object, encryptedKeys json.Object) (json.Object, error)) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `select set_config('eve.rotating', 'true', true)`)
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+
+	var m Metadata
+	err = tx.QueryRowxContext(ctx, `
+		select id, description, value, encrypted_keys, created_at, updated_at
+		from metadata
+		where id = $1
+		for update
+	`, metadataID).StructScan(&m)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return errors.WrapTx(tx, NotFoundErrorf("metadata with id: %d not found", metadataID))
+		}
+		return errors.WrapTx(tx, err)
+	}
+
+	type historyValue struct {
+		ID    int         `db:"id"`
+		Value json.Object `db:"value"`
+	}
+	var history []historyValue
+	rows, err := tx.QueryxContext(ctx, `
+		select id, value from metadata_history where metadata_id = $1
+	`, metadataID)
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+	for rows.Next() {
+		var h historyValue
+		if err = rows.StructScan(&h); err != nil {
+			_ = rows.Close()
+			return errors.WrapTx(tx, err)
+		}
+		history = append(history, h)
+	}
+	if err = rows.Close(); err != nil {
+		return errors.WrapTx(tx, err)
+	}
+
+	for _, h := range history {
+		value, err := rotate(h.Value, m.EncryptedKeys)
+		if err != nil {
+			return errors.WrapTx(tx, err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			update metadata_history set value = $1 where id = $2
+		`, value, h.ID)
+		if err != nil {
+			return errors.WrapTx(tx, err)
+		}
+	}
+
+	value, err := rotate(m.Value, m.EncryptedKeys)
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		update metadata set value = $1, updated_at = $2 where id = $3
+	`, value, time.Now().UTC(), metadataID)
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+	return nil
+}
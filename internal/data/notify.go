@@ -0,0 +1,16 @@
+package data
+
+import (
+	"context"
+
+	"github.com/unanet/go/pkg/errors"
+)
+
+// Notify sends a postgres notification to every connection listening on the channel
+func (r *Repo) Notify(ctx context.Context, channel string, payload string) error {
+	_, err := r.db.ExecContext(ctx, "select pg_notify($1, $2)", channel, payload)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	return nil
+}
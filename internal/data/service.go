@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	goErrors "errors"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -47,28 +48,6 @@ type Service struct {
 	Count           int            `db:"count"`
 }
 
-func (r *Repo) UpdateDeployedServiceVersion(ctx context.Context, id int, version string) error {
-	result, err := r.db.ExecContext(ctx, `
-		update service 
-		set deployed_version = $1, 
-		    updated_at = $2 
-		where id = $3
-	`, version, time.Now().UTC(), id)
-	if err != nil {
-		return errors.Wrap(err)
-	}
-
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err)
-	}
-
-	if affected == 0 {
-		return errors.Wrapf("the following id: %d was not found to update in service table", id)
-	}
-	return nil
-}
-
 func (r *Repo) DeployedServicesByNamespaceID(ctx context.Context, namespaceID int) (DeployServices, error) {
 	rows, err := r.db.QueryxContext(ctx, `
 		select s.id as service_id,
@@ -154,6 +133,7 @@ func (r *Repo) ServiceByName(ctx context.Context, name string, namespace string)
 		       s.namespace_id, 
 		       s.artifact_id, 
 		       s.override_version,
+		       s.deployed_version,
 		       s.count,
 		       s.created_at,
 		       s.updated_at,
@@ -184,6 +164,7 @@ func (r *Repo) ServiceByID(ctx context.Context, id int) (*Service, error) {
 		       s.namespace_id, 
 		       s.artifact_id, 
 		       s.override_version,
+		       s.deployed_version,
 		       s.count,
 		       s.created_at,
 		       s.updated_at,
@@ -285,26 +266,98 @@ func (r *Repo) UpdateService(ctx context.Context, service *Service) error {
 	return nil
 }
 
-func (r *Repo) UpdateServiceCount(ctx context.Context, serviceID int, count int) error {
+// UpdateServiceCounts changes the number of replicas for the services and records who changed them. It's all or
+// nothing, either every service is scaled or none are. The previous count of each service is returned by id
+func (r *Repo) UpdateServiceCounts(ctx context.Context, serviceIDs []int, count int, user string) (map[int]int, error) {
 	if count > 2 || count < 0 {
-		return errors.BadRequest("service count must be between > -1 and less than 3")
+		return nil, errors.BadRequest("service count must be between > -1 and less than 3")
 	}
-	result, err := r.db.ExecContext(ctx, `
-		update service set count = $1 where id = $2
-	`, count, serviceID)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return errors.Wrap(err)
+		return nil, errors.Wrap(err)
 	}
 
-	affected, err := result.RowsAffected()
+	now := time.Now().UTC()
+	fromCounts := make(map[int]int, len(serviceIDs))
+	for _, serviceID := range serviceIDs {
+		var fromCount int
+		err = tx.QueryRowxContext(ctx, "select count from service where id = $1 for update", serviceID).Scan(&fromCount)
+		if err != nil {
+			if goErrors.Is(err, sql.ErrNoRows) {
+				return nil, errors.WrapTx(tx, errors.NotFoundf("service id: %d not found", serviceID))
+			}
+			return nil, errors.WrapTx(tx, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			update service set count = $1, updated_at = $2 where id = $3
+		`, count, now, serviceID)
+		if err != nil {
+			return nil, errors.WrapTx(tx, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			insert into service_count_history(service_id, from_count, to_count, "user", created_at)
+			values ($1, $2, $3, $4, $5)
+		`, serviceID, fromCount, count, user, now)
+		if err != nil {
+			return nil, errors.WrapTx(tx, err)
+		}
+		fromCounts[serviceID] = fromCount
+	}
+
+	err = tx.Commit()
 	if err != nil {
-		return errors.Wrap(err)
+		return nil, errors.WrapTx(tx, err)
 	}
+	return fromCounts, nil
+}
 
-	if affected == 0 {
-		return errors.NotFoundf("service id: %d not found", serviceID)
+type ServiceCountChange struct {
+	ID        int          `db:"id"`
+	ServiceID int          `db:"service_id"`
+	FromCount int          `db:"from_count"`
+	ToCount   int          `db:"to_count"`
+	User      string       `db:"user"`
+	CreatedAt sql.NullTime `db:"created_at"`
+}
+
+// ServiceCountHistory returns the recorded count changes newest first, a limit of 0 returns every matching row
+func (r *Repo) ServiceCountHistory(ctx context.Context, limit int, offset int, whereArgs ...WhereArg) ([]ServiceCountChange, error) {
+	esql, args := CheckWhereArgs(`
+		select sch.id,
+		       sch.service_id,
+		       sch.from_count,
+		       sch.to_count,
+		       sch."user",
+		       sch.created_at
+		from service_count_history sch
+	`, whereArgs)
+
+	esql = fmt.Sprintf("%s order by sch.created_at desc, sch.id desc", esql)
+	if limit > 0 {
+		esql = fmt.Sprintf("%s limit $%d offset $%d", esql, len(args)+1, len(args)+2)
+		args = append(args, limit, offset)
 	}
-	return nil
+
+	rows, err := r.db.QueryxContext(ctx, esql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var changes []ServiceCountChange
+	for rows.Next() {
+		var change ServiceCountChange
+		err = rows.StructScan(&change)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
 }
 
 func (r *Repo) CreateService(ctx context.Context, model *Service) error {
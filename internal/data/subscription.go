@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"time"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+)
+
+type Subscription struct {
+	ID          int            `db:"id"`
+	Name        string         `db:"name"`
+	URL         string         `db:"url"`
+	Secret      sql.NullString `db:"secret"`
+	Events      json.Object    `db:"events"`
+	Environment sql.NullString `db:"environment"`
+	Namespace   sql.NullString `db:"namespace"`
+	Artifact    sql.NullString `db:"artifact"`
+	Disabled    bool           `db:"disabled"`
+	CreatedAt   sql.NullTime   `db:"created_at"`
+	UpdatedAt   sql.NullTime   `db:"updated_at"`
+}
+
+type Subscriptions []Subscription
+
+func (r *Repo) SubscriptionByID(ctx context.Context, id int) (*Subscription, error) {
+	var s Subscription
+	row := r.db.QueryRowxContext(ctx, "select * from subscription where id = $1", id)
+	err := row.StructScan(&s)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, NotFoundErrorf("subscription with id: %d, not found", id)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	return &s, nil
+}
+
+func (r *Repo) Subscriptions(ctx context.Context) (Subscriptions, error) {
+	return r.subscriptions(ctx, "select * from subscription order by name")
+}
+
+// SubscriptionsByEvent returns the enabled subscriptions to an event type, the environment, namespace and artifact
+// filters are left to the caller
+func (r *Repo) SubscriptionsByEvent(ctx context.Context, event string) (Subscriptions, error) {
+	return r.subscriptions(ctx, "select * from subscription where disabled = false and events @> jsonb_build_array($1::text) order by id", event)
+}
+
+func (r *Repo) subscriptions(ctx context.Context, query string, args ...interface{}) (Subscriptions, error) {
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer rows.Close()
+
+	var subscriptions Subscriptions
+	for rows.Next() {
+		var s Subscription
+		err = rows.StructScan(&s)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		subscriptions = append(subscriptions, s)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *Repo) CreateSubscription(ctx context.Context, s *Subscription) error {
+	now := time.Now().UTC()
+	s.CreatedAt = sql.NullTime{Time: now, Valid: true}
+	s.UpdatedAt = sql.NullTime{Time: now, Valid: true}
+	err := r.db.QueryRowxContext(ctx, `
+		insert into subscription(name, url, secret, events, environment, namespace, artifact, disabled, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		returning id
+	`, s.Name, s.URL, s.Secret, s.Events, s.Environment, s.Namespace, s.Artifact, s.Disabled, now).
+		Scan(&s.ID)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	return nil
+}
+
+// UpdateSubscription saves the subscription, a null secret keeps the one that's already stored
+func (r *Repo) UpdateSubscription(ctx context.Context, s *Subscription) error {
+	now := time.Now().UTC()
+	s.UpdatedAt = sql.NullTime{Time: now, Valid: true}
+	row := r.db.QueryRowxContext(ctx, `
+		update subscription
+		set name = $2,
+		    url = $3,
+		    secret = coalesce($4, secret),
+		    events = $5,
+		    environment = $6,
+		    namespace = $7,
+		    artifact = $8,
+		    disabled = $9,
+		    updated_at = $10
+		where id = $1
+		returning *
+	`, s.ID, s.Name, s.URL, s.Secret, s.Events, s.Environment, s.Namespace, s.Artifact, s.Disabled, now)
+	err := row.StructScan(s)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return NotFoundErrorf("subscription with id: %d, not found", s.ID)
+		}
+		return errors.Wrap(err)
+	}
+	return nil
+}
+
+func (r *Repo) DeleteSubscription(ctx context.Context, id int) error {
+	return r.deleteByID(ctx, "subscription", id)
+}
@@ -0,0 +1,45 @@
+package data
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/unanet/go/pkg/errors"
+)
+
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx carrying the api user that metadata and definition history is attributed to
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the api user set with WithUser, changes made outside of the api don't have one
+func UserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userContextKey{}).(string)
+	return user
+}
+
+// asUser runs fn in a transaction with eve.user set to the api user, the history triggers fall back to the
+// database user when it's empty
+func (r *Repo) asUser(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `select set_config('eve.user', $1, true)`, UserFromContext(ctx))
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+
+	if err = fn(tx); err != nil {
+		return errors.WrapTx(tx, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.WrapTx(tx, err)
+	}
+	return nil
+}
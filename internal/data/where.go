@@ -51,6 +51,25 @@ func OrWhere(key string, value interface{}) WhereArg {
 	}
 }
 
+func WhereGreaterOrEqual(key string, value interface{}) WhereArg {
+	return func(clause *WhereClause) {
+		clause.AddClause(fmt.Sprintf("%s>=?", key), ANDLogicalOperator, value)
+	}
+}
+
+func WhereLessThan(key string, value interface{}) WhereArg {
+	return func(clause *WhereClause) {
+		clause.AddClause(fmt.Sprintf("%s<?", key), ANDLogicalOperator, value)
+	}
+}
+
+// WhereExpr adds a raw expression where every ? is replaced with the next positional parameter
+func WhereExpr(expr string, values ...interface{}) WhereArg {
+	return func(clause *WhereClause) {
+		clause.AddClause(expr, ANDLogicalOperator, values...)
+	}
+}
+
 type Clause struct {
 	operator LogicalOperator
 	value    string
@@ -0,0 +1,43 @@
+package crud
+
+import (
+	"context"
+
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func (m *Manager) DeadLetters(ctx context.Context, query eve.HistoryQuery) ([]eve.DeadLetter, error) {
+	if query.Limit < 0 || query.Offset < 0 {
+		return nil, errors.BadRequest("limit and offset must be positive values")
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultHistoryLimit
+	} else if query.Limit > maxHistoryLimit {
+		query.Limit = maxHistoryLimit
+	}
+
+	var whereArgs []data.WhereArg
+	if query.From != nil {
+		whereArgs = append(whereArgs, data.WhereGreaterOrEqual("created_at", query.From.UTC()))
+	}
+
+	if query.To != nil {
+		whereArgs = append(whereArgs, data.WhereLessThan("created_at", query.To.UTC()))
+	}
+
+	dbMessages, err := m.repo.DeadLetterMessages(ctx, query.Limit, query.Offset, whereArgs...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	deadLetters := make([]eve.DeadLetter, 0, len(dbMessages))
+	for _, x := range dbMessages {
+		deadLetters = append(deadLetters, eve.ToDeadLetter(x))
+	}
+
+	return deadLetters, nil
+}
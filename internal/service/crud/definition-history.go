@@ -0,0 +1,95 @@
+package crud
+
+import (
+	"context"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+	"github.com/unanet/go/pkg/errors"
+)
+
+// DefinitionRevisions pages through a definition's revisions newest first, each with the keys it changed from the
+// revision before it. Deleted definitions keep their history
+func (m *Manager) DefinitionRevisions(ctx context.Context, id int, query eve.HistoryQuery) ([]eve.DefinitionHistory, error) {
+	query, err := historyPage(query)
+	if err != nil {
+		return nil, err
+	}
+
+	whereArgs := historyRange(query, "created", []data.WhereArg{data.Where("definition_id", id)})
+	dbResults, err := m.repo.DefinitionRevisions(ctx, query.Limit, query.Offset, whereArgs...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	revisions := make([]eve.DefinitionHistory, 0, len(dbResults))
+	for i, x := range dbResults {
+		revisions = append(revisions, fromDataDefinitionHistory(x))
+		if i > 0 {
+			revisions[i-1].Changes = historyChanges(revisions[i].Data, revisions[i-1].Data)
+		}
+	}
+
+	// the oldest revision on the page is diffed against the one before it, which is on the next page or out of range
+	if last := len(revisions) - 1; last >= 0 {
+		previous, err := m.repo.DefinitionRevisions(ctx, 1, 0, data.Where("definition_id", id), data.WhereLessThan("id", revisions[last].Revision))
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		var before map[string]interface{}
+		if len(previous) > 0 {
+			before = previous[0].Data.AsMapOrEmpty()
+		}
+		revisions[last].Changes = historyChanges(before, revisions[last].Data)
+	}
+
+	return revisions, nil
+}
+
+// RestoreDefinitionRevision sets the definition's data back to what it was at the revision, the restore is recorded
+// as a new revision. Deleted definitions are created again under the revision's description with a new id
+func (m *Manager) RestoreDefinitionRevision(ctx context.Context, id int, revision int) (*eve.Definition, error) {
+	dbRevision, err := m.repo.DefinitionRevision(ctx, id, revision)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	definition := eve.Definition{
+		Description:      dbRevision.Description,
+		DefinitionTypeID: dbRevision.DefinitionTypeID,
+		Data:             dbRevision.Data.AsMapOrEmpty(),
+	}
+
+	current, err := m.repo.GetDefinition(ctx, id)
+	if err == nil {
+		definition.Description = current.Description
+	} else if _, ok := err.(data.NotFoundError); !ok {
+		return nil, errors.Wrap(err)
+	}
+
+	if err = m.CreateDefinition(ctx, &definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+func fromDataDefinitionHistory(dbModel data.DefinitionHistory) eve.DefinitionHistory {
+	deletedTime := &dbModel.Deleted.Time
+	if !dbModel.Deleted.Valid {
+		deletedTime = nil
+	}
+
+	return eve.DefinitionHistory{
+		Revision:         dbModel.ID,
+		DefinitionID:     dbModel.DefinitionID,
+		Description:      dbModel.Description,
+		DefinitionTypeID: dbModel.DefinitionTypeID,
+		Data:             dbModel.Data.AsMapOrEmpty(),
+		Created:          dbModel.Created.Time,
+		CreatedBy:        dbModel.CreatedBy,
+		Deleted:          deletedTime,
+		DeletedBy:        dbModel.DeletedBy,
+	}
+}
@@ -2,14 +2,25 @@ package crud
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	uuid "github.com/satori/go.uuid"
 	"github.com/unanet/go/pkg/errors"
 
+	"github.com/unanet/eve/internal/data"
 	"github.com/unanet/eve/internal/service"
 	"github.com/unanet/eve/pkg/eve"
 )
 
+const (
+	defaultDeploymentLimit = 50
+	maxDeploymentLimit     = 500
+)
+
 func (m *Manager) Deployment(ctx context.Context, id string) (*eve.Deployment, error) {
 	uID, err := uuid.FromString(id)
 	if err != nil {
@@ -22,5 +33,185 @@ func (m *Manager) Deployment(ctx context.Context, id string) (*eve.Deployment, e
 	}
 
 	deployment := eve.ToDeployment(*d)
+
+	artifacts, err := m.repo.DeploymentArtifactsByDeploymentID(ctx, uID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	for _, x := range artifacts {
+		deployment.Results = append(deployment.Results, eve.DeploymentResult{
+			ServiceID:        int(x.ServiceID.Int32),
+			JobID:            int(x.JobID.Int32),
+			Name:             x.Name,
+			RequestedVersion: x.RequestedVersion.String,
+			DeployedVersion:  x.DeployedVersion.String,
+			AvailableVersion: x.ToVersion,
+			Result:           eve.ParseDeployArtifactResult(x.Result),
+			ExitCode:         x.ExitCode,
+			CreatedAt:        x.CreatedAt.Time,
+		})
+	}
+
+	messages, err := m.repo.DeploymentMessagesByDeploymentID(ctx, uID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	for _, x := range messages {
+		deployment.Messages = append(deployment.Messages, eve.DeploymentMessage{
+			Source:    x.Source,
+			Message:   x.Message,
+			CreatedAt: x.CreatedAt.Time,
+		})
+	}
+
 	return &deployment, nil
 }
+
+func (m *Manager) Deployments(ctx context.Context, query eve.DeploymentQuery) (*eve.DeploymentPage, error) {
+	if query.Limit < 0 {
+		return nil, errors.BadRequest("limit must be a positive value")
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultDeploymentLimit
+	} else if query.Limit > maxDeploymentLimit {
+		query.Limit = maxDeploymentLimit
+	}
+
+	whereArgs, err := deploymentWhereArgs(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// we ask for one extra row so we know whether there is another page
+	dbDeployments, err := m.repo.Deployments(ctx, query.Limit+1, query.Ascending, whereArgs...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	page := eve.DeploymentPage{
+		Deployments: make([]eve.Deployment, 0, len(dbDeployments)),
+	}
+
+	if len(dbDeployments) > query.Limit {
+		dbDeployments = dbDeployments[:query.Limit]
+		last := dbDeployments[len(dbDeployments)-1]
+		page.NextCursor = encodeDeploymentCursor(last.CreatedAt.Time, last.ID)
+	}
+
+	for _, x := range dbDeployments {
+		page.Deployments = append(page.Deployments, eve.ToDeployment(x))
+	}
+
+	return &page, nil
+}
+
+func deploymentWhereArgs(query eve.DeploymentQuery) ([]data.WhereArg, error) {
+	var whereArgs []data.WhereArg
+
+	if query.Environment != "" {
+		if id, err := strconv.Atoi(query.Environment); err == nil {
+			whereArgs = append(whereArgs, data.Where("d.environment_id", id))
+		} else {
+			whereArgs = append(whereArgs, data.Where("e.name", query.Environment))
+		}
+	}
+
+	if query.Namespace != "" {
+		if id, err := strconv.Atoi(query.Namespace); err == nil {
+			whereArgs = append(whereArgs, data.Where("d.namespace_id", id))
+		} else {
+			whereArgs = append(whereArgs, data.Where("n.name", query.Namespace))
+		}
+	}
+
+	if query.User != "" {
+		whereArgs = append(whereArgs, data.Where(`d."user"`, query.User))
+	}
+
+	if query.State != "" {
+		state := data.DeploymentState(strings.ToLower(query.State))
+		if eve.ParseDeploymentState(state) == eve.DeploymentStateUnknown {
+			return nil, errors.BadRequestf("invalid deployment state: %s", query.State)
+		}
+		whereArgs = append(whereArgs, data.Where("d.state", state))
+	}
+
+	if query.Type != "" {
+		switch eve.PlanType(strings.ToLower(query.Type)) {
+		case eve.DeploymentPlanTypeApplication, eve.DeploymentPlanTypeJob, eve.DeploymentPlanTypeRestart:
+			whereArgs = append(whereArgs, data.Where("d.plan_options->>'type'", strings.ToLower(query.Type)))
+		default:
+			return nil, errors.BadRequestf("invalid deployment plan type: %s", query.Type)
+		}
+	}
+
+	if query.Artifact != "" {
+		whereArgs = append(whereArgs, data.WhereExpr(
+			"(d.plan_options->'artifacts' @> jsonb_build_array(jsonb_build_object('name', ?::text)) or d.plan_options->'artifacts' @> jsonb_build_array(jsonb_build_object('artifact_name', ?::text)))",
+			query.Artifact, query.Artifact))
+	}
+
+	if query.CronID != "" {
+		cronID, err := uuid.FromString(query.CronID)
+		if err != nil {
+			return nil, errors.BadRequest("invalid deployment cron id")
+		}
+		whereArgs = append(whereArgs, data.WhereExpr(
+			"d.id in (select deployment_id from deployment_cron_job where deployment_cron_id = ?)", cronID))
+	}
+
+	if query.From != nil {
+		whereArgs = append(whereArgs, data.WhereGreaterOrEqual("d.created_at", query.From.UTC()))
+	}
+
+	if query.To != nil {
+		whereArgs = append(whereArgs, data.WhereLessThan("d.created_at", query.To.UTC()))
+	}
+
+	if query.Cursor != "" {
+		createdAt, id, err := decodeDeploymentCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		operator := "<"
+		if query.Ascending {
+			operator = ">"
+		}
+		whereArgs = append(whereArgs, data.WhereExpr(fmt.Sprintf("(d.created_at, d.id) %s (?, ?)", operator), createdAt, id))
+	}
+
+	return whereArgs, nil
+}
+
+func encodeDeploymentCursor(createdAt time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s|%s", createdAt.UTC().Format(time.RFC3339Nano), id)))
+}
+
+func decodeDeploymentCursor(cursor string) (time.Time, uuid.UUID, error) {
+	invalid := errors.BadRequest("invalid deployment cursor")
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+
+	parts := strings.SplitN(string(b), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, uuid.Nil, invalid
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+
+	id, err := uuid.FromString(parts[1])
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+
+	return createdAt, id, nil
+}
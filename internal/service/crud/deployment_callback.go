@@ -0,0 +1,48 @@
+package crud
+
+import (
+	"context"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+// DeploymentCallbacks returns the callbacks for a deployment along with every delivery attempt that was made
+func (m *Manager) DeploymentCallbacks(ctx context.Context, id string) ([]eve.DeploymentCallback, error) {
+	uID, err := uuid.FromString(id)
+	if err != nil {
+		return nil, errors.NewRestError(400, "invalid deployment id")
+	}
+
+	_, err = m.repo.DeploymentByID(ctx, uID)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	dbCallbacks, err := m.repo.DeploymentCallbacksByDeploymentID(ctx, uID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	attempts, err := m.repo.DeploymentCallbackAttemptsByDeploymentID(ctx, uID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	deliveries := make(map[int][]eve.DeploymentCallbackDelivery)
+	for _, x := range attempts {
+		deliveries[x.DeploymentCallbackID] = append(deliveries[x.DeploymentCallbackID], eve.ToDeploymentCallbackDelivery(x))
+	}
+
+	callbacks := make([]eve.DeploymentCallback, 0, len(dbCallbacks))
+	for _, x := range dbCallbacks {
+		callback := eve.ToDeploymentCallback(x)
+		callback.Deliveries = deliveries[x.ID]
+		callbacks = append(callbacks, callback)
+	}
+
+	return callbacks, nil
+}
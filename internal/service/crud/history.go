@@ -0,0 +1,62 @@
+package crud
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+// historyPage checks the paging of a history query and fills in the default limit
+func historyPage(query eve.HistoryQuery) (eve.HistoryQuery, error) {
+	if query.Limit < 0 || query.Offset < 0 {
+		return query, errors.BadRequest("limit and offset must be positive values")
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultHistoryLimit
+	} else if query.Limit > maxHistoryLimit {
+		query.Limit = maxHistoryLimit
+	}
+	return query, nil
+}
+
+// historyRange limits a history query on the column holding when the revision was created
+func historyRange(query eve.HistoryQuery, column string, whereArgs []data.WhereArg) []data.WhereArg {
+	if query.From != nil {
+		whereArgs = append(whereArgs, data.WhereGreaterOrEqual(column, query.From.UTC()))
+	}
+
+	if query.To != nil {
+		whereArgs = append(whereArgs, data.WhereLessThan(column, query.To.UTC()))
+	}
+	return whereArgs
+}
+
+// historyChanges returns the top level keys that differ from before to after sorted by key, the first revision
+// has nothing before it so every key is added
+func historyChanges(before, after map[string]interface{}) []eve.HistoryChange {
+	var changes []eve.HistoryChange
+	for key, value := range after {
+		previous, ok := before[key]
+		if !ok {
+			changes = append(changes, eve.HistoryChange{Key: key, Type: eve.HistoryChangeAdded, After: value})
+		} else if !reflect.DeepEqual(previous, value) {
+			changes = append(changes, eve.HistoryChange{Key: key, Type: eve.HistoryChangeChanged, Before: previous, After: value})
+		}
+	}
+
+	for key, previous := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, eve.HistoryChange{Key: key, Type: eve.HistoryChangeRemoved, Before: previous})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
@@ -0,0 +1,48 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func TestHistoryChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		before map[string]interface{}
+		after  map[string]interface{}
+		want   []eve.HistoryChange
+	}{
+		{
+			name:  "first revision adds every key",
+			after: map[string]interface{}{"b": 2.0, "a": "x"},
+			want: []eve.HistoryChange{
+				{Key: "a", Type: eve.HistoryChangeAdded, After: "x"},
+				{Key: "b", Type: eve.HistoryChangeAdded, After: 2.0},
+			},
+		},
+		{
+			name:   "added removed and changed keys",
+			before: map[string]interface{}{"keep": "x", "gone": true, "nested": map[string]interface{}{"a": 1.0}},
+			after:  map[string]interface{}{"keep": "x", "new": "y", "nested": map[string]interface{}{"a": 2.0}},
+			want: []eve.HistoryChange{
+				{Key: "gone", Type: eve.HistoryChangeRemoved, Before: true},
+				{Key: "nested", Type: eve.HistoryChangeChanged, Before: map[string]interface{}{"a": 1.0}, After: map[string]interface{}{"a": 2.0}},
+				{Key: "new", Type: eve.HistoryChangeAdded, After: "y"},
+			},
+		},
+		{
+			name:   "unchanged",
+			before: map[string]interface{}{"a": []interface{}{"x"}},
+			after:  map[string]interface{}{"a": []interface{}{"x"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := historyChanges(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("historyChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
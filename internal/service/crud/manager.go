@@ -2,17 +2,20 @@ package crud
 
 import (
 	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/pkg/envelope"
 	"github.com/unanet/eve/pkg/eve"
 )
 
-func NewManager(r *data.Repo) *Manager {
+func NewManager(r *data.Repo, keyring *envelope.Keyring) *Manager {
 	return &Manager{
-		repo: r,
+		repo:    r,
+		keyring: keyring,
 	}
 }
 
 type Manager struct {
-	repo *data.Repo
+	repo    *data.Repo
+	keyring *envelope.Keyring
 }
 
 // TODO: Handle this with Data default defs applied to everything (service/jobs)
@@ -2,7 +2,11 @@ package crud
 
 import (
 	"context"
+	"sort"
+
 	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/envelope"
 	"github.com/unanet/eve/pkg/eve"
 	"github.com/unanet/go/pkg/errors"
 	"github.com/unanet/go/pkg/json"
@@ -17,6 +21,80 @@ func (m *Manager) MetadataHistory(ctx context.Context) (models []eve.MetadataHis
 	return fromDataMetadataHistoryList(dbResults), nil
 }
 
+// MetadataRevisions pages through a metadata's revisions newest first, each with the keys it changed from the
+// revision before it. Deleted metadata keeps its history
+func (m *Manager) MetadataRevisions(ctx context.Context, id int, query eve.HistoryQuery) ([]eve.MetadataHistory, error) {
+	query, err := historyPage(query)
+	if err != nil {
+		return nil, err
+	}
+
+	whereArgs := historyRange(query, "created", []data.WhereArg{data.Where("metadata_id", id)})
+	dbResults, err := m.repo.MetadataRevisions(ctx, query.Limit, query.Offset, whereArgs...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	revisions := make([]eve.MetadataHistory, 0, len(dbResults))
+	for i, x := range dbResults {
+		revisions = append(revisions, fromDataMetadataHistory(x))
+		if i > 0 {
+			revisions[i-1].Changes = historyChanges(revisions[i].Value, revisions[i-1].Value)
+		}
+	}
+
+	// the oldest revision on the page is diffed against the one before it, which is on the next page or out of range
+	if last := len(revisions) - 1; last >= 0 {
+		previous, err := m.repo.MetadataRevisions(ctx, 1, 0, data.Where("metadata_id", id), data.WhereLessThan("id", revisions[last].Revision))
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		var before map[string]interface{}
+		if len(previous) > 0 {
+			before = previous[0].Value.AsMapOrEmpty()
+		}
+		revisions[last].Changes = historyChanges(before, revisions[last].Value)
+	}
+
+	return revisions, nil
+}
+
+// RestoreMetadataRevision sets the metadata's value back to what it was at the revision, the restore is recorded
+// as a new revision. Deleted metadata is created again under the revision's description with a new id
+func (m *Manager) RestoreMetadataRevision(ctx context.Context, id int, revision int) (*eve.Metadata, error) {
+	dbRevision, err := m.repo.MetadataRevision(ctx, id, revision)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	metadata := eve.Metadata{
+		Description: dbRevision.Description,
+		Value:       dbRevision.Value.AsMapOrEmpty(),
+	}
+
+	current, err := m.repo.GetMetadata(ctx, id)
+	if err == nil {
+		metadata.Description = current.Description
+		metadata.EncryptedKeys = encryptedKeysList(current.EncryptedKeys)
+	} else if _, ok := err.(data.NotFoundError); ok {
+		// the marked keys went with the row, the ones still held in an envelope were marked at the revision
+		for k, v := range metadata.Value {
+			if _, ok := envelope.Encrypted(v); ok {
+				metadata.EncryptedKeys = append(metadata.EncryptedKeys, k)
+			}
+		}
+		sort.Strings(metadata.EncryptedKeys)
+	} else {
+		return nil, errors.Wrap(err)
+	}
+
+	if err = m.CreateMetadata(ctx, &metadata); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
 func fromDataMetadataHistory(dbModel data.MetadataHistory) eve.MetadataHistory {
 	deletedTime := &dbModel.Deleted.Time
 	if !dbModel.Deleted.Valid {
@@ -24,6 +102,7 @@ func fromDataMetadataHistory(dbModel data.MetadataHistory) eve.MetadataHistory {
 	}
 
 	return eve.MetadataHistory{
+		Revision:    dbModel.ID,
 		MetadataId:  dbModel.MetadataId,
 		Description: dbModel.Description,
 		Value:       dbModel.Value.AsMapOrEmpty(),
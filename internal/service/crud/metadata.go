@@ -75,19 +75,21 @@ func toDataMetadataJobMap(m eve.MetadataJobMap) data.MetadataJobMap {
 
 func toDataMetadata(m eve.Metadata) data.Metadata {
 	return data.Metadata{
-		ID:          m.ID,
-		Description: m.Description,
-		Value:       json.FromMapOrEmpty(m.Value),
+		ID:            m.ID,
+		Description:   m.Description,
+		Value:         json.FromMapOrEmpty(m.Value),
+		EncryptedKeys: encryptedKeysObject(m.EncryptedKeys),
 	}
 }
 
 func fromDataMetadata(m data.Metadata) eve.Metadata {
 	return eve.Metadata{
-		ID:          m.ID,
-		Description: m.Description,
-		Value:       m.Value.AsMapOrEmpty(),
-		CreatedAt:   m.CreatedAt.Time,
-		UpdatedAt:   m.UpdatedAt.Time,
+		ID:            m.ID,
+		Description:   m.Description,
+		Value:         m.Value.AsMapOrEmpty(),
+		EncryptedKeys: encryptedKeysList(m.EncryptedKeys),
+		CreatedAt:     m.CreatedAt.Time,
+		UpdatedAt:     m.UpdatedAt.Time,
 	}
 }
 
@@ -170,6 +172,7 @@ func fromDataMetadataService(m data.MetadataService) eve.MetadataServiceMap {
 		EnvironmentID: int(m.MapEnvironmentID.Int32),
 		ArtifactID:    int(m.MapArtifactID.Int32),
 		NamespaceID:   int(m.MapNamespaceID.Int32),
+		ClusterID:     int(m.MapClusterID.Int32),
 		ServiceID:     int(m.MapServiceID.Int32),
 		StackingOrder: m.StackingOrder,
 		CreatedAt:     m.CreatedAt.Time,
@@ -194,8 +197,23 @@ func (m Manager) Metadata(ctx context.Context) ([]eve.Metadata, error) {
 }
 
 func (m Manager) CreateMetadata(ctx context.Context, metadata *eve.Metadata) error {
+	// leaving encrypted_keys out keeps the keys already marked as encrypted, only an empty list clears them
+	if metadata.EncryptedKeys == nil || len(metadata.EncryptedKeys) > 0 {
+		encryptedKeys, err := m.mergeEncryptedKeys(ctx, metadata.Description, metadata.EncryptedKeys)
+		if err != nil {
+			return err
+		}
+		metadata.EncryptedKeys = encryptedKeys
+	}
+
+	value, err := m.encryptMetadata(metadata.Value, metadata.EncryptedKeys)
+	if err != nil {
+		return err
+	}
+	metadata.Value = value
+
 	dataMetadata := toDataMetadata(*metadata)
-	err := m.repo.UpsertMetadata(ctx, &dataMetadata)
+	err = m.repo.UpsertMetadata(ctx, &dataMetadata)
 	if err != nil {
 		return errors.Wrap(err)
 	}
@@ -203,12 +221,25 @@ func (m Manager) CreateMetadata(ctx context.Context, metadata *eve.Metadata) err
 	metadata.UpdatedAt = dataMetadata.UpdatedAt.Time
 	metadata.CreatedAt = dataMetadata.CreatedAt.Time
 	metadata.ID = dataMetadata.ID
+	m.publishMetadataChanged(ctx, metadata)
 	return nil
 }
 
 func (m Manager) UpsertMergeMetadata(ctx context.Context, metadata *eve.Metadata) error {
+	encryptedKeys, err := m.mergeEncryptedKeys(ctx, metadata.Description, metadata.EncryptedKeys)
+	if err != nil {
+		return err
+	}
+	metadata.EncryptedKeys = encryptedKeys
+
+	value, err := m.encryptMetadata(metadata.Value, metadata.EncryptedKeys)
+	if err != nil {
+		return err
+	}
+	metadata.Value = value
+
 	dataMetadata := toDataMetadata(*metadata)
-	err := m.repo.UpsertMergeMetadata(ctx, &dataMetadata)
+	err = m.repo.UpsertMergeMetadata(ctx, &dataMetadata)
 	if err != nil {
 		return errors.Wrap(err)
 	}
@@ -217,6 +248,7 @@ func (m Manager) UpsertMergeMetadata(ctx context.Context, metadata *eve.Metadata
 	metadata.CreatedAt = dataMetadata.CreatedAt.Time
 	metadata.ID = dataMetadata.ID
 	metadata.Value = dataMetadata.Value.AsMapOrEmpty()
+	m.publishMetadataChanged(ctx, metadata)
 	return nil
 }
 
@@ -226,7 +258,9 @@ func (m Manager) DeleteMetadataKey(ctx context.Context, id int, key string) (eve
 		return eve.Metadata{}, service.CheckForNotFoundError(err)
 	}
 
-	return fromDataMetadata(*metadata), nil
+	result := fromDataMetadata(*metadata)
+	m.publishMetadataChanged(ctx, result)
+	return result, nil
 }
 
 func (m *Manager) GetMetadata(ctx context.Context, id string) (*eve.Metadata, error) {
@@ -252,6 +286,7 @@ func (m *Manager) DeleteMetadata(ctx context.Context, id int) error {
 	if err != nil {
 		return service.CheckForNotFoundError(err)
 	}
+	m.publishMetadataChanged(ctx, eve.Metadata{ID: id})
 	return nil
 }
 
@@ -298,6 +333,7 @@ func (m *Manager) CreateMetadataJobMap(ctx context.Context, model *eve.MetadataJ
 	}
 
 	model.CreatedAt = dbModel.CreatedAt.Time
+	m.publishMetadataChanged(ctx, model)
 
 	return nil
 }
@@ -309,6 +345,7 @@ func (m *Manager) CreateMetadataServiceMap(ctx context.Context, model *eve.Metad
 	}
 
 	model.CreatedAt = dbModel.CreatedAt.Time
+	m.publishMetadataChanged(ctx, model)
 
 	return nil
 }
@@ -340,6 +377,7 @@ func (m *Manager) UpsertMetadataJobMap(ctx context.Context, e *eve.MetadataJobMa
 
 	e.UpdatedAt = dataMetadataJobMap.UpdatedAt.Time
 	e.CreatedAt = dataMetadataJobMap.CreatedAt.Time
+	m.publishMetadataChanged(ctx, e)
 	return nil
 }
 
@@ -352,6 +390,7 @@ func (m *Manager) UpsertMetadataServiceMap(ctx context.Context, serviceMap *eve.
 
 	serviceMap.UpdatedAt = dataMetadataServiceMap.UpdatedAt.Time
 	serviceMap.CreatedAt = dataMetadataServiceMap.CreatedAt.Time
+	m.publishMetadataChanged(ctx, serviceMap)
 	return nil
 }
 
@@ -360,6 +399,7 @@ func (m *Manager) DeleteMetadataJobMap(ctx context.Context, metadataID int, desc
 	if err != nil {
 		return service.CheckForNotFoundError(err)
 	}
+	m.publishMetadataChanged(ctx, eve.MetadataJobMap{MetadataID: metadataID, Description: description})
 
 	return nil
 }
@@ -369,6 +409,7 @@ func (m *Manager) DeleteMetadataServiceMap(ctx context.Context, metadataID int,
 	if err != nil {
 		return service.CheckForNotFoundError(err)
 	}
+	m.publishMetadataChanged(ctx, eve.MetadataServiceMap{MetadataID: metadataID, Description: description})
 
 	return nil
 }
@@ -409,3 +450,12 @@ func (m *Manager) mergeMetadata(metadataList []eve.MetadataField) eve.MetadataFi
 
 	return mergedMetadata
 }
+
+// publishMetadataChanged lets subscribers know a metadata value or one of its maps changed, changed is the
+// metadata or map
+func (m *Manager) publishMetadataChanged(ctx context.Context, changed interface{}) {
+	m.Publish(ctx, eve.Event{
+		Type: eve.EventMetadataChanged,
+		Data: changed,
+	})
+}
@@ -0,0 +1,197 @@
+package crud
+
+import (
+	"context"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+	"github.com/unanet/go/pkg/log"
+	"go.uber.org/zap"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/envelope"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func encryptedKeysObject(keys eve.StringList) json.Object {
+	if keys == nil {
+		keys = eve.StringList{}
+	}
+	o, _ := json.StructToJsonObject(keys)
+	return o
+}
+
+func encryptedKeysList(o json.Object) eve.StringList {
+	var keys eve.StringList
+	_ = o.Unmarshal(&keys)
+	return keys
+}
+
+// encryptMetadata returns a copy of the value with the encrypted keys encrypted, values that are already encrypted
+// (e.g. read back from the api and saved again) are left alone
+func (m *Manager) encryptMetadata(value eve.MetadataField, keys eve.StringList) (eve.MetadataField, error) {
+	if len(keys) == 0 || value == nil {
+		return value, nil
+	}
+
+	encrypted := make(eve.MetadataField, len(value))
+	for k, v := range value {
+		encrypted[k] = v
+		if !keys.Contains(k) {
+			continue
+		}
+		if _, ok := envelope.Encrypted(v); ok {
+			continue
+		}
+		if !m.keyring.Enabled() {
+			return nil, errors.BadRequestf("metadata key: %s can't be encrypted, metadata encryption is not configured", k)
+		}
+		ev, err := m.keyring.Encrypt(v)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		encrypted[k] = ev
+	}
+
+	return encrypted, nil
+}
+
+func (m *Manager) decryptMetadata(value eve.MetadataField) (eve.MetadataField, error) {
+	decrypted := make(eve.MetadataField, len(value))
+	for k, v := range value {
+		dv, err := m.keyring.Decrypt(v)
+		if err != nil {
+			return nil, errors.Wrapf("failed to decrypt metadata key: %s, %s", k, err)
+		}
+		decrypted[k] = dv
+	}
+	return decrypted, nil
+}
+
+// DecryptedServiceMetadata is ServiceMetadata with the encrypted values decrypted, it's only for building plans
+func (m *Manager) DecryptedServiceMetadata(ctx context.Context, id int) (eve.MetadataField, error) {
+	metadata, err := m.repo.ServiceMetadata(ctx, id)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	var collectedMetadata []eve.MetadataField
+	for _, x := range metadata {
+		value, err := m.decryptMetadata(x.Metadata.AsMapOrEmpty())
+		if err != nil {
+			return nil, errors.Wrapf("metadata: %s, %s", x.MetadataDescription, err)
+		}
+		collectedMetadata = append(collectedMetadata, value)
+	}
+
+	return m.mergeMetadata(collectedMetadata), nil
+}
+
+// DecryptedJobMetadata is JobMetadata with the encrypted values decrypted, it's only for building plans
+func (m *Manager) DecryptedJobMetadata(ctx context.Context, id int) (eve.MetadataField, error) {
+	metadata, err := m.repo.JobMetadata(ctx, id)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	var collectedMetadata []eve.MetadataField
+	for _, x := range metadata {
+		value, err := m.decryptMetadata(x.Metadata.AsMapOrEmpty())
+		if err != nil {
+			return nil, errors.Wrapf("metadata: %s, %s", x.MetadataDescription, err)
+		}
+		collectedMetadata = append(collectedMetadata, value)
+	}
+
+	return m.mergeMetadata(collectedMetadata), nil
+}
+
+// rotateMetadataValue re-encrypts the encrypted values with the current master key, plain text values of keys that
+// have since been marked as encrypted are encrypted. Values encrypted under a key that's no longer marked (e.g. in
+// history rows) are re-encrypted too so they don't depend on a retired master key
+func (m *Manager) rotateMetadataValue(value json.Object, encryptedKeys json.Object) (json.Object, error) {
+	keys := encryptedKeysList(encryptedKeys)
+	fields := value.AsMapOrEmpty()
+	for k, v := range fields {
+		if _, ok := envelope.Encrypted(v); !ok && !keys.Contains(k) {
+			continue
+		}
+		dv, err := m.keyring.Decrypt(v)
+		if err != nil {
+			return nil, errors.Wrapf("failed to decrypt metadata key: %s, %s", k, err)
+		}
+		ev, err := m.keyring.Encrypt(dv)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		fields[k] = ev
+	}
+
+	rotated, err := json.FromMap(fields)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return rotated, nil
+}
+
+// RotateMetadataEncryption re-encrypts every metadata row, and its history, that has encrypted keys or encrypted
+// history values with the current master key. Marking a key as encrypted only encrypts the values written from then
+// on, the history rows from before keep the plain text until this is run. It returns the number of metadata rows that
+// were rotated
+func (m *Manager) RotateMetadataEncryption(ctx context.Context) (int, error) {
+	if !m.keyring.Enabled() {
+		return 0, errors.Wrapf("metadata encryption is not configured")
+	}
+
+	metadata, err := m.repo.Metadata(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err)
+	}
+
+	// a row with no encrypted keys left can still have history values encrypted under a retired master key
+	encryptedHistory, err := m.repo.MetadataWithEncryptedHistory(ctx, envelope.Key)
+	if err != nil {
+		return 0, errors.Wrap(err)
+	}
+	hasEncryptedHistory := make(map[int]bool, len(encryptedHistory))
+	for _, id := range encryptedHistory {
+		hasEncryptedHistory[id] = true
+	}
+
+	var rotated int
+	for _, x := range metadata {
+		if len(encryptedKeysList(x.EncryptedKeys)) == 0 && !hasEncryptedHistory[x.ID] {
+			continue
+		}
+
+		err = m.repo.RotateMetadata(ctx, x.ID, m.rotateMetadataValue)
+		if err != nil {
+			return rotated, errors.Wrapf("failed to rotate metadata: %s, %s", x.Description, err)
+		}
+		log.Logger.Info("metadata encryption rotated", zap.Int("metadata_id", x.ID), zap.String("description", x.Description))
+		rotated++
+	}
+
+	return rotated, nil
+}
+
+// mergeEncryptedKeys returns the keys already marked as encrypted for an existing record along with the new ones,
+// a merge can only ever add encrypted keys
+func (m *Manager) mergeEncryptedKeys(ctx context.Context, description string, keys eve.StringList) (eve.StringList, error) {
+	existing, err := m.repo.GetMetadataByDescription(ctx, description)
+	if err != nil {
+		if _, ok := err.(data.NotFoundError); ok {
+			return keys, nil
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	merged := encryptedKeysList(existing.EncryptedKeys)
+	for _, k := range keys {
+		if !merged.Contains(k) {
+			merged = append(merged, k)
+		}
+	}
+	return merged, nil
+}
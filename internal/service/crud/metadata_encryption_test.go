@@ -0,0 +1,64 @@
+package crud
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/unanet/go/pkg/json"
+
+	"github.com/unanet/eve/pkg/envelope"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func testKeyring(t *testing.T, current string) *envelope.Keyring {
+	keyring, err := envelope.New(envelope.Config{
+		MetadataEncryptionKeys: map[string]string{
+			"old": base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32))),
+			"new": base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32))),
+		},
+		MetadataEncryptionKeyID: current,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestManager_rotateMetadataValue(t *testing.T) {
+	old := testKeyring(t, "old")
+	unmarked, err := old.Encrypt("since unmarked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	marked, err := old.Encrypt("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := json.FromMap(map[string]interface{}{
+		"password":  marked,
+		"token":     "plain text from before it was marked",
+		"unmarked":  unmarked,
+		"log_level": "info",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := Manager{keyring: testKeyring(t, "new")}
+	rotated, err := m.rotateMetadataValue(value, encryptedKeysObject(eve.StringList{"password", "token"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := rotated.AsMapOrEmpty()
+	for _, k := range []string{"password", "token", "unmarked"} {
+		if keyID, ok := envelope.Encrypted(fields[k]); !ok || keyID != "new" {
+			t.Errorf("%s key id = %s, %v, want new", k, keyID, ok)
+		}
+	}
+	if fields["log_level"] != "info" {
+		t.Errorf("log_level = %v", fields["log_level"])
+	}
+}
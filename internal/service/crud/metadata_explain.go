@@ -0,0 +1,157 @@
+package crud
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+// metadataContribution is a matching metadata map row along with its metadata value
+type metadataContribution struct {
+	source eve.MetadataSource
+	value  eve.MetadataField
+}
+
+// mapScope is what a metadata or definition map row is keyed on
+type mapScope struct {
+	artifactID    int32
+	environmentID int32
+	namespaceID   int32
+	clusterID     int32
+	serviceID     int32
+	jobID         int32
+}
+
+// String describes the scope, e.g. "environment" or "artifact+namespace"
+func (s mapScope) String() string {
+	var scope []string
+	for _, x := range []struct {
+		name string
+		id   int32
+	}{
+		{"artifact", s.artifactID},
+		{"environment", s.environmentID},
+		{"namespace", s.namespaceID},
+		{"cluster", s.clusterID},
+		{"service", s.serviceID},
+		{"job", s.jobID},
+	} {
+		if x.id > 0 {
+			scope = append(scope, x.name)
+		}
+	}
+	return strings.Join(scope, "+")
+}
+
+func serviceMetadataContributions(metadata []data.MetadataService) []metadataContribution {
+	var contributions []metadataContribution
+	for _, x := range metadata {
+		contributions = append(contributions, metadataContribution{
+			source: eve.MetadataSource{
+				MetadataID:          x.MetadataID,
+				MetadataDescription: x.MetadataDescription,
+				MapDescription:      x.MapDescription,
+				StackingOrder:       x.StackingOrder,
+				Scope: mapScope{
+					artifactID:    x.MapArtifactID.Int32,
+					environmentID: x.MapEnvironmentID.Int32,
+					namespaceID:   x.MapNamespaceID.Int32,
+					clusterID:     x.MapClusterID.Int32,
+					serviceID:     x.MapServiceID.Int32,
+				}.String(),
+			},
+			value: x.Metadata.AsMapOrEmpty(),
+		})
+	}
+	return contributions
+}
+
+func jobMetadataContributions(metadata []data.MetadataJob) []metadataContribution {
+	var contributions []metadataContribution
+	for _, x := range metadata {
+		contributions = append(contributions, metadataContribution{
+			source: eve.MetadataSource{
+				MetadataID:          x.MetadataID,
+				MetadataDescription: x.MetadataDescription,
+				MapDescription:      x.MapDescription,
+				StackingOrder:       x.StackingOrder,
+				Scope: mapScope{
+					artifactID:    x.MapArtifactId.Int32,
+					environmentID: x.MapEnvironmentId.Int32,
+					namespaceID:   x.MapNamespaceId.Int32,
+					clusterID:     x.MapClusterId.Int32,
+					jobID:         x.MapJobId.Int32,
+				}.String(),
+			},
+			value: x.Metadata.AsMapOrEmpty(),
+		})
+	}
+	return contributions
+}
+
+func (m *Manager) ServiceMetadataExplanation(ctx context.Context, id int) (*eve.MetadataExplanation, error) {
+	metadata, err := m.repo.ServiceMetadata(ctx, id)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	return m.explainMetadata(serviceMetadataContributions(metadata)), nil
+}
+
+func (m *Manager) JobMetadataExplanation(ctx context.Context, id int) (*eve.MetadataExplanation, error) {
+	metadata, err := m.repo.JobMetadata(ctx, id)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	return m.explainMetadata(jobMetadataContributions(metadata)), nil
+}
+
+// mergeContributions merges the contributions, which are already in stacking order, the same way ServiceMetadata and
+// JobMetadata do
+func (m *Manager) mergeContributions(contributions []metadataContribution) eve.MetadataField {
+	var collectedMetadata []eve.MetadataField
+	for _, x := range contributions {
+		collectedMetadata = append(collectedMetadata, x.value)
+	}
+	return m.mergeMetadata(collectedMetadata)
+}
+
+// explainMetadata merges the contributions and records which one won each top level key. A key whose values are all
+// maps is deep merged, so its sources are the maps it was merged with rather than values it replaced outright
+func (m *Manager) explainMetadata(contributions []metadataContribution) *eve.MetadataExplanation {
+	sources := make(map[string][]eve.MetadataSource)
+	for _, x := range contributions {
+		for key, value := range x.value {
+			source := x.source
+			source.Value = value
+			sources[key] = append(sources[key], source)
+		}
+	}
+
+	merged := m.mergeContributions(contributions)
+	explanation := eve.MetadataExplanation{
+		Metadata: merged,
+		Keys:     make([]eve.MetadataKeyExplanation, 0, len(merged)),
+	}
+
+	for key, value := range merged {
+		keySources := sources[key]
+		explanation.Keys = append(explanation.Keys, eve.MetadataKeyExplanation{
+			Key:      key,
+			Value:    value,
+			Source:   keySources[len(keySources)-1],
+			Overrode: keySources[:len(keySources)-1],
+		})
+	}
+
+	sort.Slice(explanation.Keys, func(i, j int) bool {
+		return explanation.Keys[i].Key < explanation.Keys[j].Key
+	})
+
+	return &explanation
+}
@@ -0,0 +1,48 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func TestManager_explainMetadata(t *testing.T) {
+	m := Manager{}
+	explanation := m.explainMetadata([]metadataContribution{
+		{
+			source: eve.MetadataSource{MetadataID: 1, MapDescription: "env", Scope: "environment", StackingOrder: 1},
+			value:  eve.MetadataField{"db": "env-db", "log_level": "info"},
+		},
+		{
+			source: eve.MetadataSource{MetadataID: 2, MapDescription: "svc", Scope: "service", StackingOrder: 5},
+			value:  eve.MetadataField{"db": "svc-db"},
+		},
+	})
+
+	if len(explanation.Keys) != 2 || explanation.Keys[0].Key != "db" || explanation.Keys[1].Key != "log_level" {
+		t.Fatalf("unexpected keys: %+v", explanation.Keys)
+	}
+
+	db := explanation.Keys[0]
+	if db.Value != "svc-db" || db.Source.MetadataID != 2 || db.Source.Value != "svc-db" {
+		t.Errorf("db should come from the service map: %+v", db)
+	}
+	if len(db.Overrode) != 1 || db.Overrode[0].MetadataID != 1 || db.Overrode[0].Value != "env-db" {
+		t.Errorf("db should override the environment map: %+v", db.Overrode)
+	}
+
+	logLevel := explanation.Keys[1]
+	if logLevel.Source.Scope != "environment" || len(logLevel.Overrode) != 0 {
+		t.Errorf("log_level should only come from the environment map: %+v", logLevel)
+	}
+
+	if explanation.Metadata["db"] != "svc-db" {
+		t.Errorf("merged metadata = %v", explanation.Metadata)
+	}
+}
+
+func TestMapScope_String(t *testing.T) {
+	if got := (mapScope{namespaceID: 3, artifactID: 7}).String(); got != "artifact+namespace" {
+		t.Errorf("mapScope.String() = %s", got)
+	}
+}
@@ -0,0 +1,491 @@
+package crud
+
+import (
+	"context"
+	gojson "encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+// dataScope is the scope as the data layer takes it, the services and jobs it applies to are looked up with the same
+// predicate as repo.ServiceMetadata and repo.JobMetadata
+func (s mapScope) dataScope() data.MapScope {
+	return data.MapScope{
+		ArtifactID:    s.artifactID,
+		EnvironmentID: s.environmentID,
+		NamespaceID:   s.namespaceID,
+		ClusterID:     s.clusterID,
+		ServiceID:     s.serviceID,
+		JobID:         s.jobID,
+	}
+}
+
+// previewOrder returns the order the current map rows (given by description and stacking order) would be merged in
+// once the proposed map is saved, the proposed map is -1. Saving a map upserts on its description so a current row
+// with the same description is replaced, and it drops out entirely when the proposed map no longer applies
+func previewOrder(descriptions []string, stackingOrders []int, description string, stackingOrder int, applies bool) []int {
+	var order []int
+	inserted := !applies
+	for i := range descriptions {
+		if descriptions[i] == description {
+			continue
+		}
+		if !inserted && stackingOrders[i] > stackingOrder {
+			order = append(order, -1)
+			inserted = true
+		}
+		order = append(order, i)
+	}
+	if !inserted {
+		order = append(order, -1)
+	}
+	return order
+}
+
+func previewMetadataContributions(current []metadataContribution, proposed metadataContribution, applies bool) []metadataContribution {
+	var descriptions []string
+	var stackingOrders []int
+	for _, x := range current {
+		descriptions = append(descriptions, x.source.MapDescription)
+		stackingOrders = append(stackingOrders, x.source.StackingOrder)
+	}
+
+	var preview []metadataContribution
+	for _, i := range previewOrder(descriptions, stackingOrders, proposed.source.MapDescription, proposed.source.StackingOrder, applies) {
+		if i < 0 {
+			preview = append(preview, proposed)
+			continue
+		}
+		preview = append(preview, current[i])
+	}
+	return preview
+}
+
+// previewServices returns the services affected by the scopes, and which of them the first (proposed) scope applies to
+func (m *Manager) previewServices(ctx context.Context, scopes ...mapScope) ([]data.Service, map[int]bool, error) {
+	var services []data.Service
+	applies := make(map[int]bool)
+	seen := make(map[int]bool)
+	for i, scope := range scopes {
+		scoped, err := m.repo.ServicesInMapScope(ctx, scope.dataScope())
+		if err != nil {
+			return nil, nil, errors.Wrap(err)
+		}
+		for _, x := range scoped {
+			if i == 0 {
+				applies[x.ID] = true
+			}
+			if !seen[x.ID] {
+				seen[x.ID] = true
+				services = append(services, x)
+			}
+		}
+	}
+	return services, applies, nil
+}
+
+// previewJobs returns the jobs affected by the scopes, and which of them the first (proposed) scope applies to
+func (m *Manager) previewJobs(ctx context.Context, scopes ...mapScope) ([]data.Job, map[int]bool, error) {
+	var jobs []data.Job
+	applies := make(map[int]bool)
+	seen := make(map[int]bool)
+	for i, scope := range scopes {
+		scoped, err := m.repo.JobsInMapScope(ctx, scope.dataScope())
+		if err != nil {
+			return nil, nil, errors.Wrap(err)
+		}
+		for _, x := range scoped {
+			if i == 0 {
+				applies[x.ID] = true
+			}
+			if !seen[x.ID] {
+				seen[x.ID] = true
+				jobs = append(jobs, x)
+			}
+		}
+	}
+	return jobs, applies, nil
+}
+
+func (m *Manager) proposedMetadataContribution(ctx context.Context, metadataID int, description string, stackingOrder int, scope mapScope) (*metadataContribution, error) {
+	metadata, err := m.repo.GetMetadata(ctx, metadataID)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	return &metadataContribution{
+		source: eve.MetadataSource{
+			MetadataID:          metadata.ID,
+			MetadataDescription: metadata.Description,
+			MapDescription:      description,
+			Scope:               scope.String(),
+			StackingOrder:       stackingOrder,
+		},
+		value: metadata.Value.AsMapOrEmpty(),
+	}, nil
+}
+
+// PreviewMetadataServiceMap returns the effective metadata before and after the map is saved for every service it
+// affects, including the services a map with the same description currently applies to. Nothing is persisted
+func (m *Manager) PreviewMetadataServiceMap(ctx context.Context, serviceMap eve.MetadataServiceMap) ([]eve.MetadataPreview, error) {
+	scopes := []mapScope{{
+		artifactID:    int32(serviceMap.ArtifactID),
+		environmentID: int32(serviceMap.EnvironmentID),
+		namespaceID:   int32(serviceMap.NamespaceID),
+		clusterID:     int32(serviceMap.ClusterID),
+		serviceID:     int32(serviceMap.ServiceID),
+	}}
+
+	proposed, err := m.proposedMetadataContribution(ctx, serviceMap.MetadataID, serviceMap.Description, serviceMap.StackingOrder, scopes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	maps, err := m.repo.MetadataServiceMaps(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	for _, x := range maps {
+		if x.Description == serviceMap.Description {
+			scopes = append(scopes, mapScope{
+				artifactID:    x.ArtifactID.Int32,
+				environmentID: x.EnvironmentID.Int32,
+				namespaceID:   x.NamespaceID.Int32,
+				clusterID:     x.ClusterID.Int32,
+				serviceID:     x.ServiceID.Int32,
+			})
+		}
+	}
+
+	services, applies, err := m.previewServices(ctx, scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]eve.MetadataPreview, 0, len(services))
+	for _, x := range services {
+		metadata, err := m.repo.ServiceMetadata(ctx, x.ID)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		current := serviceMetadataContributions(metadata)
+		before := m.mergeContributions(current)
+		after := m.mergeContributions(previewMetadataContributions(current, *proposed, applies[x.ID]))
+		previews = append(previews, eve.MetadataPreview{
+			ServiceID:     x.ID,
+			Name:          x.Name,
+			NamespaceName: x.NamespaceName,
+			Changed:       !reflect.DeepEqual(before, after),
+			Before:        before,
+			After:         after,
+		})
+	}
+
+	return previews, nil
+}
+
+// PreviewMetadataJobMap is the job equivalent of PreviewMetadataServiceMap
+func (m *Manager) PreviewMetadataJobMap(ctx context.Context, jobMap eve.MetadataJobMap) ([]eve.MetadataPreview, error) {
+	scopes := []mapScope{{
+		artifactID:    int32(jobMap.ArtifactID),
+		environmentID: int32(jobMap.EnvironmentID),
+		namespaceID:   int32(jobMap.NamespaceID),
+		clusterID:     int32(jobMap.ClusterID),
+		jobID:         int32(jobMap.JobID),
+	}}
+
+	proposed, err := m.proposedMetadataContribution(ctx, jobMap.MetadataID, jobMap.Description, jobMap.StackingOrder, scopes[0])
+	if err != nil {
+		return nil, err
+	}
+
+	maps, err := m.repo.MetadataJobMaps(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	for _, x := range maps {
+		if x.Description == jobMap.Description {
+			scopes = append(scopes, mapScope{
+				artifactID:    x.ArtifactID.Int32,
+				environmentID: x.EnvironmentID.Int32,
+				namespaceID:   x.NamespaceID.Int32,
+				clusterID:     x.ClusterID.Int32,
+				jobID:         x.JobID.Int32,
+			})
+		}
+	}
+
+	jobs, applies, err := m.previewJobs(ctx, scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]eve.MetadataPreview, 0, len(jobs))
+	for _, x := range jobs {
+		metadata, err := m.repo.JobMetadata(ctx, x.ID)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		current := jobMetadataContributions(metadata)
+		before := m.mergeContributions(current)
+		after := m.mergeContributions(previewMetadataContributions(current, *proposed, applies[x.ID]))
+		previews = append(previews, eve.MetadataPreview{
+			JobID:         x.ID,
+			Name:          x.Name,
+			NamespaceName: x.NamespaceName,
+			Changed:       !reflect.DeepEqual(before, after),
+			Before:        before,
+			After:         after,
+		})
+	}
+
+	return previews, nil
+}
+
+// definitionContribution is a matching definition map row along with its parsed definition
+type definitionContribution struct {
+	mapDescription string
+	stackingOrder  int
+	result         eve.DefinitionResult
+}
+
+func newDefinitionContribution(mapDescription string, stackingOrder int, definition json.Object, order, class, version, kind string) (definitionContribution, error) {
+	var defSpecData = make(map[string]interface{})
+	if err := gojson.Unmarshal(definition, &defSpecData); err != nil {
+		return definitionContribution{}, errors.Wrapf("failed to parse the deployment definition: %s", err)
+	}
+
+	return definitionContribution{
+		mapDescription: mapDescription,
+		stackingOrder:  stackingOrder,
+		result: eve.DefinitionResult{
+			Order:   order,
+			Class:   class,
+			Version: version,
+			Kind:    kind,
+			Data:    defSpecData,
+		},
+	}, nil
+}
+
+func previewDefinitionContributions(current []definitionContribution, proposed definitionContribution, applies bool) []definitionContribution {
+	var descriptions []string
+	var stackingOrders []int
+	for _, x := range current {
+		descriptions = append(descriptions, x.mapDescription)
+		stackingOrders = append(stackingOrders, x.stackingOrder)
+	}
+
+	var preview []definitionContribution
+	for _, i := range previewOrder(descriptions, stackingOrders, proposed.mapDescription, proposed.stackingOrder, applies) {
+		if i < 0 {
+			preview = append(preview, proposed)
+			continue
+		}
+		preview = append(preview, current[i])
+	}
+	return preview
+}
+
+// mergeDefinitionContributions merges the contributions the same way ServiceDefinitionResults and
+// JobDefinitionResults do, sorted so before and after can be compared
+func (m *Manager) mergeDefinitionContributions(contributions []definitionContribution, defaults func([]eve.DefinitionResult) []eve.DefinitionResult) (eve.DefinitionResults, error) {
+	var definitionResults []eve.DefinitionResult
+	for _, x := range contributions {
+		definitionResults = append(definitionResults, x.result)
+	}
+
+	mergedResults, err := m.mergeDefinitionData(definitionResults)
+	if err != nil {
+		return nil, errors.Wrapf("failed to merge the deployment definitions: %s", err)
+	}
+	mergedResults = defaults(mergedResults)
+
+	sort.Slice(mergedResults, func(i, j int) bool {
+		return mergedResults[i].Key() < mergedResults[j].Key()
+	})
+
+	return mergedResults, nil
+}
+
+func (m *Manager) proposedDefinitionContribution(ctx context.Context, definitionID int, description string, stackingOrder int) (*definitionContribution, error) {
+	definition, err := m.repo.GetDefinition(ctx, definitionID)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	definitionTypes, err := m.repo.DefinitionTypes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	for _, x := range definitionTypes {
+		if x.ID == definition.DefinitionTypeID {
+			contribution, err := newDefinitionContribution(description, stackingOrder, definition.Data, x.DefinitionOrder, x.Class, x.Version, x.Kind)
+			if err != nil {
+				return nil, err
+			}
+			return &contribution, nil
+		}
+	}
+
+	return nil, errors.Wrapf("definition type: %d not found for definition: %d", definition.DefinitionTypeID, definition.ID)
+}
+
+// PreviewDefinitionServiceMap returns the merged definitions before and after the map is saved for every service it
+// affects, including the services a map with the same description currently applies to. Nothing is persisted
+func (m *Manager) PreviewDefinitionServiceMap(ctx context.Context, serviceMap eve.DefinitionServiceMap) ([]eve.DefinitionPreview, error) {
+	scopes := []mapScope{{
+		artifactID:    int32(serviceMap.ArtifactID),
+		environmentID: int32(serviceMap.EnvironmentID),
+		namespaceID:   int32(serviceMap.NamespaceID),
+		clusterID:     int32(serviceMap.ClusterID),
+		serviceID:     int32(serviceMap.ServiceID),
+	}}
+
+	proposed, err := m.proposedDefinitionContribution(ctx, serviceMap.DefinitionID, serviceMap.Description, serviceMap.StackingOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	maps, err := m.repo.DefinitionServiceMaps(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	for _, x := range maps {
+		if x.Description == serviceMap.Description {
+			scopes = append(scopes, mapScope{
+				artifactID:    x.ArtifactID.Int32,
+				environmentID: x.EnvironmentID.Int32,
+				namespaceID:   x.NamespaceID.Int32,
+				clusterID:     x.ClusterID.Int32,
+				serviceID:     x.ServiceID.Int32,
+			})
+		}
+	}
+
+	services, applies, err := m.previewServices(ctx, scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]eve.DefinitionPreview, 0, len(services))
+	for _, x := range services {
+		definitions, err := m.repo.ServiceDefinition(ctx, x.ID)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		var current []definitionContribution
+		for _, d := range definitions {
+			contribution, err := newDefinitionContribution(d.MapDescription, d.StackingOrder, d.Data, d.DefinitionOrder, d.DefinitionClass, d.DefinitionVersion, d.DefinitionKind)
+			if err != nil {
+				return nil, err
+			}
+			current = append(current, contribution)
+		}
+
+		before, err := m.mergeDefinitionContributions(current, m.defaultServiceDefinitions)
+		if err != nil {
+			return nil, err
+		}
+		after, err := m.mergeDefinitionContributions(previewDefinitionContributions(current, *proposed, applies[x.ID]), m.defaultServiceDefinitions)
+		if err != nil {
+			return nil, err
+		}
+
+		previews = append(previews, eve.DefinitionPreview{
+			ServiceID:     x.ID,
+			Name:          x.Name,
+			NamespaceName: x.NamespaceName,
+			Changed:       !reflect.DeepEqual(before, after),
+			Before:        before,
+			After:         after,
+		})
+	}
+
+	return previews, nil
+}
+
+// PreviewDefinitionJobMap is the job equivalent of PreviewDefinitionServiceMap
+func (m *Manager) PreviewDefinitionJobMap(ctx context.Context, jobMap eve.DefinitionJobMap) ([]eve.DefinitionPreview, error) {
+	scopes := []mapScope{{
+		artifactID:    int32(jobMap.ArtifactID),
+		environmentID: int32(jobMap.EnvironmentID),
+		namespaceID:   int32(jobMap.NamespaceID),
+		clusterID:     int32(jobMap.ClusterID),
+		jobID:         int32(jobMap.JobID),
+	}}
+
+	proposed, err := m.proposedDefinitionContribution(ctx, jobMap.DefinitionID, jobMap.Description, jobMap.StackingOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	maps, err := m.repo.DefinitionJobMaps(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	for _, x := range maps {
+		if x.Description == jobMap.Description {
+			scopes = append(scopes, mapScope{
+				artifactID:    x.ArtifactID.Int32,
+				environmentID: x.EnvironmentID.Int32,
+				namespaceID:   x.NamespaceID.Int32,
+				clusterID:     x.ClusterID.Int32,
+				jobID:         x.JobID.Int32,
+			})
+		}
+	}
+
+	jobs, applies, err := m.previewJobs(ctx, scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]eve.DefinitionPreview, 0, len(jobs))
+	for _, x := range jobs {
+		definitions, err := m.repo.JobDefinition(ctx, x.ID)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		var current []definitionContribution
+		for _, d := range definitions {
+			contribution, err := newDefinitionContribution(d.MapDescription, d.StackingOrder, d.Data, d.DefinitionOrder, d.DefinitionClass, d.DefinitionVersion, d.DefinitionKind)
+			if err != nil {
+				return nil, err
+			}
+			current = append(current, contribution)
+		}
+
+		before, err := m.mergeDefinitionContributions(current, m.defaultJobDefinitions)
+		if err != nil {
+			return nil, err
+		}
+		after, err := m.mergeDefinitionContributions(previewDefinitionContributions(current, *proposed, applies[x.ID]), m.defaultJobDefinitions)
+		if err != nil {
+			return nil, err
+		}
+
+		previews = append(previews, eve.DefinitionPreview{
+			JobID:         x.ID,
+			Name:          x.Name,
+			NamespaceName: x.NamespaceName,
+			Changed:       !reflect.DeepEqual(before, after),
+			Before:        before,
+			After:         after,
+		})
+	}
+
+	return previews, nil
+}
@@ -0,0 +1,33 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreviewOrder(t *testing.T) {
+	descriptions := []string{"env", "ns", "svc"}
+	stackingOrders := []int{1, 5, 10}
+
+	tests := []struct {
+		name          string
+		description   string
+		stackingOrder int
+		applies       bool
+		want          []int
+	}{
+		{"new map stacks by order", "new", 5, true, []int{0, 1, -1, 2}},
+		{"new map lowest order", "new", 0, true, []int{-1, 0, 1, 2}},
+		{"new map highest order", "new", 20, true, []int{0, 1, 2, -1}},
+		{"existing map is replaced", "ns", 20, true, []int{0, 2, -1}},
+		{"existing map no longer applies", "ns", 5, false, []int{0, 2}},
+		{"new map doesn't apply", "new", 5, false, []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previewOrder(descriptions, stackingOrders, tt.description, tt.stackingOrder, tt.applies); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("previewOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
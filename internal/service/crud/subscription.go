@@ -0,0 +1,165 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+	"github.com/unanet/go/pkg/log"
+	"go.uber.org/zap"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: len(value) > 0}
+}
+
+func toDataSubscription(s eve.Subscription) (data.Subscription, error) {
+	events, err := json.StructToJsonObject(s.Events)
+	if err != nil {
+		return data.Subscription{}, errors.Wrap(err)
+	}
+
+	return data.Subscription{
+		ID:          s.ID,
+		Name:        s.Name,
+		URL:         s.URL,
+		Secret:      nullString(s.Secret),
+		Events:      events,
+		Environment: nullString(s.Environment),
+		Namespace:   nullString(s.Namespace),
+		Artifact:    nullString(s.Artifact),
+		Disabled:    s.Disabled,
+	}, nil
+}
+
+// fromDataSubscription never hands back the secret, it's only ever written
+func fromDataSubscription(s data.Subscription) eve.Subscription {
+	subscription := eve.Subscription{
+		ID:          s.ID,
+		Name:        s.Name,
+		URL:         s.URL,
+		Environment: s.Environment.String,
+		Namespace:   s.Namespace.String,
+		Artifact:    s.Artifact.String,
+		Disabled:    s.Disabled,
+		CreatedAt:   s.CreatedAt.Time,
+		UpdatedAt:   s.UpdatedAt.Time,
+	}
+	_ = s.Events.Unmarshal(&subscription.Events)
+	return subscription
+}
+
+func (m *Manager) Subscriptions(ctx context.Context) ([]eve.Subscription, error) {
+	dbSubscriptions, err := m.repo.Subscriptions(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	subscriptions := make([]eve.Subscription, 0, len(dbSubscriptions))
+	for _, x := range dbSubscriptions {
+		subscriptions = append(subscriptions, fromDataSubscription(x))
+	}
+
+	return subscriptions, nil
+}
+
+func (m *Manager) Subscription(ctx context.Context, id int) (*eve.Subscription, error) {
+	dbSubscription, err := m.repo.SubscriptionByID(ctx, id)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	subscription := fromDataSubscription(*dbSubscription)
+	return &subscription, nil
+}
+
+func (m *Manager) CreateSubscription(ctx context.Context, model *eve.Subscription) error {
+	dbSubscription, err := toDataSubscription(*model)
+	if err != nil {
+		return err
+	}
+
+	err = m.repo.CreateSubscription(ctx, &dbSubscription)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	*model = fromDataSubscription(dbSubscription)
+	return nil
+}
+
+// UpdateSubscription replaces the subscription, leaving the secret out keeps the existing one
+func (m *Manager) UpdateSubscription(ctx context.Context, model *eve.Subscription) error {
+	dbSubscription, err := toDataSubscription(*model)
+	if err != nil {
+		return err
+	}
+
+	err = m.repo.UpdateSubscription(ctx, &dbSubscription)
+	if err != nil {
+		return service.CheckForNotFoundError(err)
+	}
+
+	*model = fromDataSubscription(dbSubscription)
+	return nil
+}
+
+func (m *Manager) DeleteSubscription(ctx context.Context, id int) error {
+	err := m.repo.DeleteSubscription(ctx, id)
+	if err != nil {
+		return service.CheckForNotFoundError(err)
+	}
+	return nil
+}
+
+// Publish adds a callback to the outbox for every subscription that matches the event. Failures are only logged,
+// publishing an event never fails whatever triggered it
+func (m *Manager) Publish(ctx context.Context, event eve.Event) {
+	logger := log.Logger.With(zap.String("req_id", log.GetReqID(ctx)), zap.String("event", string(event.Type)))
+
+	subscriptions, err := m.repo.SubscriptionsByEvent(ctx, string(event.Type))
+	if err != nil {
+		logger.Warn("failed to get the event subscriptions", zap.Error(err))
+		return
+	}
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	event.ID = uuid.NewV4()
+	event.CreatedAt = time.Now().UTC()
+	body, err := json.StructToJsonObject(event)
+	if err != nil {
+		logger.Warn("failed to marshal the event", zap.Error(err))
+		return
+	}
+
+	for _, x := range subscriptions {
+		if !fromDataSubscription(x).Matches(event) {
+			continue
+		}
+
+		callback := data.DeploymentCallback{
+			SubscriptionID: sql.NullInt32{Int32: int32(x.ID), Valid: true},
+			Event:          nullString(string(event.Type)),
+			URL:            x.URL,
+			Body:           body,
+		}
+		if event.DeploymentID != nil {
+			callback.DeploymentID = uuid.NullUUID{UUID: *event.DeploymentID, Valid: true}
+		}
+
+		err = m.repo.CreateDeploymentCallback(ctx, &callback)
+		if err != nil {
+			logger.Warn("failed to queue the subscription callback", zap.Int("subscription_id", x.ID), zap.Error(err))
+		}
+	}
+}
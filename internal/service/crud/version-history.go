@@ -0,0 +1,107 @@
+package crud
+
+import (
+	"context"
+
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+func (m *Manager) ServiceVersionHistory(ctx context.Context, serviceID int, query eve.HistoryQuery) ([]eve.VersionHistory, error) {
+	if _, err := m.repo.ServiceByID(ctx, serviceID); err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	return m.versionHistory(ctx, query, data.Where("da.service_id", serviceID))
+}
+
+// ServiceScaleHistory returns who changed the replica count of a service and when
+func (m *Manager) ServiceScaleHistory(ctx context.Context, serviceID int, query eve.HistoryQuery) ([]eve.ScaleHistory, error) {
+	if _, err := m.repo.ServiceByID(ctx, serviceID); err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	query, err := historyPage(query)
+	if err != nil {
+		return nil, err
+	}
+
+	whereArgs := historyRange(query, "sch.created_at", []data.WhereArg{data.Where("sch.service_id", serviceID)})
+	dbResults, err := m.repo.ServiceCountHistory(ctx, query.Limit, query.Offset, whereArgs...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	list := make([]eve.ScaleHistory, 0, len(dbResults))
+	for _, x := range dbResults {
+		list = append(list, eve.ScaleHistory{
+			ID:        x.ID,
+			ServiceID: x.ServiceID,
+			FromCount: x.FromCount,
+			Count:     x.ToCount,
+			User:      x.User,
+			CreatedAt: x.CreatedAt.Time,
+		})
+	}
+	return list, nil
+}
+
+func (m *Manager) JobVersionHistory(ctx context.Context, jobID int, query eve.HistoryQuery) ([]eve.VersionHistory, error) {
+	if _, err := m.repo.JobByID(ctx, jobID); err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	return m.versionHistory(ctx, query, data.Where("da.job_id", jobID))
+}
+
+func (m *Manager) versionHistory(ctx context.Context, query eve.HistoryQuery, whereArgs ...data.WhereArg) ([]eve.VersionHistory, error) {
+	query, err := historyPage(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// noop results are kept for the deployment itself but are not a version transition
+	whereArgs = append(whereArgs, data.WhereExpr("da.result <> ?", eve.DeployArtifactResultNoop.String()))
+	whereArgs = historyRange(query, "da.created_at", whereArgs)
+
+	dbResults, err := m.repo.DeploymentArtifacts(ctx, query.Limit, query.Offset, whereArgs...)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	return fromDataVersionHistoryList(dbResults), nil
+}
+
+func fromDataVersionHistory(dbModel data.DeploymentArtifact) eve.VersionHistory {
+	return eve.VersionHistory{
+		ID:            dbModel.ID,
+		DeploymentID:  dbModel.DeploymentID,
+		EnvironmentID: dbModel.EnvironmentID,
+		NamespaceID:   dbModel.NamespaceID,
+		ServiceID:     int(dbModel.ServiceID.Int32),
+		JobID:         int(dbModel.JobID.Int32),
+		Name:          dbModel.Name,
+		FromVersion:   dbModel.FromVersion.String,
+		ToVersion:     dbModel.ToVersion,
+		Result:        eve.ParseDeployArtifactResult(dbModel.Result),
+		ExitCode:      dbModel.ExitCode,
+		User:          dbModel.User,
+		CreatedAt:     dbModel.CreatedAt.Time,
+	}
+}
+
+func fromDataVersionHistoryList(dbModels data.DeploymentArtifacts) []eve.VersionHistory {
+	list := make([]eve.VersionHistory, 0, len(dbModels))
+	for _, x := range dbModels {
+		list = append(list, fromDataVersionHistory(x))
+	}
+	return list
+}
@@ -1,51 +1,118 @@
 package plans
 
 import (
+	"bytes"
 	"context"
-	"fmt"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
 	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/dghubble/sling"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	"github.com/unanet/go/pkg/errors"
 	ehttp "github.com/unanet/go/pkg/http"
-	"github.com/unanet/go/pkg/json"
 )
 
 const (
 	userAgent = "eve"
+
+	// CallbackSignatureHeader holds "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" using the target's secret
+	CallbackSignatureHeader = "X-Eve-Signature"
+	CallbackTimestampHeader = "X-Eve-Timestamp"
+	CallbackDeliveryHeader  = "X-Eve-Delivery"
+
+	// maxCallbackFailureBody is how much of a failed response is kept in the delivery log
+	maxCallbackFailureBody = 1024
 )
 
+// CallbackSecrets holds the signing secrets keyed by the callback url's host name, the Default secret is used for any
+// host without its own. Callbacks without a secret are sent unsigned
+type CallbackSecrets struct {
+	Default string
+	Hosts   map[string]string
+}
+
+func (s CallbackSecrets) For(url string) string {
+	u, err := neturl.Parse(url)
+	if err == nil {
+		if secret, ok := s.Hosts[strings.ToLower(u.Hostname())]; ok {
+			return secret
+		}
+	}
+	return s.Default
+}
+
+// SignCallback returns the signature header value for a body sent at the supplied unix timestamp
+func SignCallback(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
 type Callback struct {
-	sling *sling.Sling
+	client  *http.Client
+	secrets CallbackSecrets
 }
 
-func NewCallback(timeout time.Duration) *Callback {
+func NewCallback(timeout time.Duration, secrets CallbackSecrets) *Callback {
 	var httpClient = &http.Client{
 		Timeout:   timeout,
-		Transport: ehttp.LoggingTransport,
+		Transport: otelhttp.NewTransport(ehttp.LoggingTransport),
 	}
 
-	slingClient := sling.New().Client(httpClient).
-		Add("User-Agent", userAgent).
-		ResponseDecoder(json.NewJsonDecoder())
-	return &Callback{sling: slingClient}
+	lowered := make(map[string]string, len(secrets.Hosts))
+	for k, v := range secrets.Hosts {
+		lowered[strings.ToLower(k)] = v
+	}
+	secrets.Hosts = lowered
+
+	return &Callback{client: httpClient, secrets: secrets}
 }
 
-func (c *Callback) Post(ctx context.Context, url string, body interface{}) error {
-	var failure string
-	r, err := c.sling.New().Post(url).BodyJSON(body).Request()
+// Post sends a single signed delivery of the body and returns the response status code, anything other than a 2xx
+// is returned as an error. A status code of 0 means there wasn't a response. The secret is used to sign the body when
+// it's supplied, otherwise the configured secret for the url's host is used
+func (c *Callback) Post(ctx context.Context, id int, url string, secret string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return errors.Wrap(err)
+		return 0, errors.Wrap(err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CallbackDeliveryHeader, strconv.Itoa(id))
+	req.Header.Set(CallbackTimestampHeader, timestamp)
+	if len(secret) == 0 {
+		secret = c.secrets.For(url)
 	}
-	resp, err := c.sling.Do(r.WithContext(ctx), nil, &failure)
+	if len(secret) > 0 {
+		req.Header.Set(CallbackSignatureHeader, SignCallback(secret, timestamp, body))
+	}
+
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return errors.Wrap(err)
+		statCallbackCount.WithLabelValues("error").Inc()
+		return 0, errors.Wrap(err)
 	}
-	if http.StatusOK == resp.StatusCode {
-		return nil
-	} else {
-		return errors.Wrap(fmt.Errorf(failure))
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		statCallbackCount.WithLabelValues("success").Inc()
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return resp.StatusCode, nil
 	}
+
+	statCallbackCount.WithLabelValues("failure").Inc()
+	failure, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxCallbackFailureBody))
+	return resp.StatusCode, errors.Wrapf("callback returned status: %d, %s", resp.StatusCode, strings.TrimSpace(string(failure)))
 }
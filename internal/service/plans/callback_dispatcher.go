@@ -0,0 +1,242 @@
+package plans
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+	"github.com/unanet/go/pkg/log"
+	"go.uber.org/zap"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+	"github.com/unanet/eve/pkg/queue"
+)
+
+const (
+	callbackDispatchInterval = 2 * time.Second
+	callbackDispatchLimit    = 50
+	// callbackDispatchConcurrency bounds how many callbacks of a run are posted at once
+	callbackDispatchConcurrency = 5
+	maxCallbackErrorLength      = 2000
+	// callbackLeaseMargin covers looking up the signing secret and recording the attempts on top of the posts
+	callbackLeaseMargin = 30 * time.Second
+)
+
+type HttpCallback interface {
+	Post(ctx context.Context, id int, url string, secret string, body []byte) (int, error)
+}
+
+type CallbackDispatcherRepo interface {
+	DueDeploymentCallbacks(ctx context.Context, limit int, lease time.Duration) (data.DeploymentCallbacks, error)
+	RecordDeploymentCallbackAttempt(ctx context.Context, c *data.DeploymentCallback, attempt *data.DeploymentCallbackAttempt) error
+	SubscriptionByID(ctx context.Context, id int) (*data.Subscription, error)
+}
+
+// CallbackDispatcher delivers the deployment callbacks sitting in the outbox, failed deliveries are retried with
+// backoff until the retry policy runs out of attempts
+type CallbackDispatcher struct {
+	log      *zap.Logger
+	timeout  time.Duration
+	lease    time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan bool
+	repo     CallbackDispatcherRepo
+	callback HttpCallback
+	policy   queue.RetryPolicy
+}
+
+// NewCallbackDispatcher sizes a run from the callback timeout so a whole batch, posted callbackDispatchConcurrency at
+// a time, can time out. The callbacks are leased for longer than that so one is never picked up again while the run
+// could still deliver it
+func NewCallbackDispatcher(repo CallbackDispatcherRepo, callback HttpCallback, policy queue.RetryPolicy, callbackTimeout time.Duration) *CallbackDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	rounds := (callbackDispatchLimit + callbackDispatchConcurrency - 1) / callbackDispatchConcurrency
+	timeout := time.Duration(rounds) * callbackTimeout
+	return &CallbackDispatcher{
+		repo:     repo,
+		callback: callback,
+		policy:   policy,
+		log:      log.Logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan bool),
+		timeout:  timeout,
+		lease:    timeout + callbackLeaseMargin,
+	}
+}
+
+func (cd *CallbackDispatcher) Start() {
+	go cd.start()
+	cd.log.Info("callback dispatcher started", zap.Int("max_attempts", cd.policy.MaxAttempts))
+}
+
+func (cd *CallbackDispatcher) run(ctx context.Context) error {
+	// the lease keeps a callback from being picked up again while it's being delivered
+	callbacks, err := cd.repo.DueDeploymentCallbacks(ctx, callbackDispatchLimit, cd.lease)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, callbackDispatchConcurrency)
+	for i := range callbacks {
+		slots <- struct{}{}
+		// anything left over once the run times out or is stopped is picked up again when its lease runs out
+		if ctx.Err() != nil {
+			<-slots
+			break
+		}
+		wg.Add(1)
+		go func(c *data.DeploymentCallback) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			cd.deliver(ctx, c)
+		}(&callbacks[i])
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// secret returns the subscription's own signing secret, callbacks that came from a plan's callback url use the host secrets
+func (cd *CallbackDispatcher) secret(ctx context.Context, c *data.DeploymentCallback) (string, error) {
+	if !c.SubscriptionID.Valid {
+		return "", nil
+	}
+
+	subscription, err := cd.repo.SubscriptionByID(ctx, int(c.SubscriptionID.Int32))
+	if err != nil {
+		return "", errors.Wrap(err)
+	}
+	return subscription.Secret.String, nil
+}
+
+func (cd *CallbackDispatcher) deliver(ctx context.Context, c *data.DeploymentCallback) {
+	now := time.Now()
+	secret, err := cd.secret(ctx, c)
+	if err != nil {
+		cd.log.Error("failed to get the callback signing secret", zap.Int("callback_id", c.ID), zap.Error(err))
+		return
+	}
+
+	statusCode, err := cd.callback.Post(ctx, c.ID, c.URL, secret, c.Body)
+	attempt := data.DeploymentCallbackAttempt{
+		ElapsedMs: int(time.Since(now).Milliseconds()),
+	}
+
+	c.Attempts++
+	c.LastStatusCode = sql.NullInt32{Int32: int32(statusCode), Valid: statusCode > 0}
+	attempt.StatusCode = c.LastStatusCode
+	if err == nil {
+		c.State = data.DeploymentCallbackStateDelivered
+		c.LastError = sql.NullString{}
+		c.DeliveredAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	} else {
+		message := err.Error()
+		if len(message) > maxCallbackErrorLength {
+			message = message[:maxCallbackErrorLength]
+		}
+		c.LastError = sql.NullString{String: message, Valid: true}
+		attempt.Error = c.LastError
+		if cd.policy.Exhausted(c.Attempts) {
+			c.State = data.DeploymentCallbackStateFailed
+		} else {
+			c.NextAttemptAt = sql.NullTime{Time: time.Now().UTC().Add(cd.policy.Backoff(c.Attempts)), Valid: true}
+		}
+		cd.log.Warn("deployment callback failed",
+			zap.Int("callback_id", c.ID),
+			zap.String("id", c.DeploymentID.UUID.String()),
+			zap.String("callback_url", c.URL),
+			zap.Int("attempts", c.Attempts),
+			zap.String("state", string(c.State)),
+			zap.Error(err),
+		)
+	}
+
+	err = cd.repo.RecordDeploymentCallbackAttempt(ctx, c, &attempt)
+	if err != nil {
+		cd.log.Error("failed to record the deployment callback attempt", zap.Int("callback_id", c.ID), zap.Error(err))
+	}
+}
+
+func (cd *CallbackDispatcher) start() {
+	for {
+		select {
+		case <-cd.ctx.Done():
+			cd.log.Info("callback dispatcher stopped")
+			close(cd.done)
+			return
+		default:
+			// Stop cancels the run, posts that are in flight are abandoned and picked up again once their lease runs out
+			ctx, cancel := context.WithTimeout(context.WithValue(cd.ctx, log.RequestIDKey, log.GetNextRequestID()), cd.timeout)
+			err := cd.run(ctx)
+			cancel()
+			if err != nil {
+				cd.log.Error("an error occurred in the callback dispatcher", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-cd.ctx.Done():
+		case <-time.After(callbackDispatchInterval):
+		}
+	}
+}
+
+func (cd *CallbackDispatcher) Stop() {
+	cd.cancel()
+	<-cd.done
+}
+
+// queueCallback adds the callback to the outbox, the dispatcher takes care of signing, delivering and retrying it.
+// Failures are only logged, a callback never fails the deployment
+func (dq *Queue) queueCallback(ctx context.Context, deploymentID uuid.UUID, url string, body interface{}) {
+	jsonBody, err := json.StructToJsonObject(body)
+	if err != nil {
+		dq.Logger(ctx).Warn("failed to marshal the deployment callback", zap.String("id", deploymentID.String()), zap.Error(err))
+		return
+	}
+
+	err = dq.repo.CreateDeploymentCallback(ctx, &data.DeploymentCallback{
+		DeploymentID: uuid.NullUUID{UUID: deploymentID, Valid: true},
+		URL:          url,
+		Body:         jsonBody,
+	})
+	if err != nil {
+		dq.Logger(ctx).Warn("failed to queue the deployment callback", zap.String("id", deploymentID.String()), zap.String("callback_url", url), zap.Error(err))
+	}
+}
+
+// RedeliverCallback puts a deployment's callback back in the outbox with a fresh set of attempts
+func (dq *Queue) RedeliverCallback(ctx context.Context, deploymentID string, callbackID int) (*eve.DeploymentCallback, error) {
+	uID, err := uuid.FromString(deploymentID)
+	if err != nil {
+		return nil, errors.NewRestError(400, "invalid deployment id")
+	}
+
+	callback, err := dq.repo.DeploymentCallbackByID(ctx, callbackID)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	if !callback.DeploymentID.Valid || !uuid.Equal(callback.DeploymentID.UUID, uID) {
+		return nil, errors.NotFoundf("callback: %d, not found for deployment: %s", callbackID, deploymentID)
+	}
+
+	callback, err = dq.repo.RedeliverDeploymentCallback(ctx, callbackID)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	result := eve.ToDeploymentCallback(*callback)
+	return &result, nil
+}
@@ -0,0 +1,45 @@
+package plans
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSignCallback(t *testing.T) {
+	body := []byte(`{"deployment_id":"1"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1600000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := SignCallback("secret", "1600000000", body); got != want {
+		t.Errorf("SignCallback() = %v, want %v", got, want)
+	}
+
+	if SignCallback("secret", "1600000001", body) == want {
+		t.Errorf("SignCallback() should change with the timestamp")
+	}
+}
+
+func TestCallbackSecrets_For(t *testing.T) {
+	secrets := CallbackSecrets{
+		Default: "default",
+		Hosts:   map[string]string{"ci.example.com": "ci"},
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://ci.example.com/hooks/eve", "ci"},
+		{"https://ci.example.com:8443/hooks/eve", "ci"},
+		{"https://other.example.com/hooks/eve", "default"},
+		{"::not a url", "default"},
+	}
+	for _, tt := range tests {
+		if got := secrets.For(tt.url); got != tt.want {
+			t.Errorf("For(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
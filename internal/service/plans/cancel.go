@@ -0,0 +1,78 @@
+package plans
+
+import (
+	"context"
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/unanet/go/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+	"github.com/unanet/eve/pkg/queue"
+)
+
+// CancelDeployment stops a deployment that hasn't finished yet. A queued deployment has its api queue message removed,
+// a scheduled deployment also gets a cancel command sent to the scheduler of the cluster it was sent to. The deployment
+// is cancelled and released even when the cancel can't be sent, the failure is added to its messages
+func (dq *Queue) CancelDeployment(ctx context.Context, id string, cancel eve.DeploymentCancel) (*eve.Deployment, error) {
+	deploymentID, err := uuid.FromString(id)
+	if err != nil {
+		return nil, errors.NewRestError(400, "invalid deployment id")
+	}
+
+	deployment, previous, err := dq.repo.CancelDeployment(ctx, deploymentID)
+	if err != nil {
+		if _, ok := err.(data.NotFoundError); ok {
+			if _, err := dq.repo.DeploymentByID(ctx, deploymentID); err != nil {
+				return nil, service.CheckForNotFoundError(err)
+			}
+			return nil, errors.BadRequestf("deployment: %s, has already finished and cannot be cancelled", deploymentID)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	var options eve.NamespacePlanOptions
+	err = deployment.PlanOptions.Unmarshal(&options)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	message := fmt.Sprintf("deployment cancelled by %s while %s", cancel.User, previous)
+	if previous == data.DeploymentStateScheduled {
+		err = dq.cancelScheduled(ctx, deployment, options)
+		if err != nil {
+			dq.Logger(ctx).Error("failed to send the cancel to the scheduler", zap.String("id", deployment.ID.String()), zap.Error(err))
+			message = fmt.Sprintf("%s, the cancel could not be sent to the scheduler: %s", message, err)
+		}
+	}
+
+	dq.releaseDeployment(ctx, deployment, options, data.DeploymentMessageSourceCancel, message)
+
+	result := eve.ToDeployment(*deployment)
+	result.Messages = append(result.Messages, eve.DeploymentMessage{
+		Source:    data.DeploymentMessageSourceCancel,
+		Message:   message,
+		CreatedAt: deployment.UpdatedAt.Time,
+	})
+	return &result, nil
+}
+
+func (dq *Queue) cancelScheduled(ctx context.Context, deployment *data.Deployment, options eve.NamespacePlanOptions) error {
+	cluster, err := dq.repo.ClusterByID(ctx, options.NamespaceRequest.ClusterID)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	// the deployment itself is still in flight in the namespace group, so the cancel has to go through its own group
+	// or the scheduler would only see it once the deployment is done
+	return dq.worker.Message(ctx, cluster.SchQueueUrl, &queue.M{
+		ID:       deployment.ID,
+		GroupID:  fmt.Sprintf("cancel-%s", options.NamespaceRequest.GetQueueGroupID()),
+		Body:     deployment.PlanLocation,
+		Command:  queue.CommandCancelNamespace,
+		DedupeID: fmt.Sprintf("cancel-%s", deployment.ID),
+	})
+}
@@ -0,0 +1,124 @@
+// +build local
+
+package plans_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unanet/go/pkg/json"
+
+	"github.com/unanet/eve/internal/config"
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service/crud"
+	"github.com/unanet/eve/internal/service/plans"
+	"github.com/unanet/eve/pkg/eve"
+	"github.com/unanet/eve/pkg/notify"
+	"github.com/unanet/eve/pkg/queue"
+)
+
+// schedulerWorker records the messages sent to the scheduler, failing them when sendErr is set
+type schedulerWorker struct {
+	sendErr error
+	sent    []*queue.M
+	deleted []*queue.M
+}
+
+func (w *schedulerWorker) Start(queue.Handler) {}
+
+func (w *schedulerWorker) Stop() {}
+
+func (w *schedulerWorker) DeleteMessage(ctx context.Context, m *queue.M) error {
+	w.deleted = append(w.deleted, m)
+	return nil
+}
+
+func (w *schedulerWorker) Message(ctx context.Context, qUrl string, m *queue.M) error {
+	if w.sendErr != nil {
+		return w.sendErr
+	}
+	w.sent = append(w.sent, m)
+	return nil
+}
+
+type discardNotifications struct{}
+
+func (discardNotifications) Notify(ctx context.Context, notification notify.Notification) {}
+
+type discardProgress struct{}
+
+func (discardProgress) Publish(ctx context.Context, p eve.DeploymentProgress) {}
+
+func createDeployment(t *testing.T, repo *data.Repo, state data.DeploymentState) *data.Deployment {
+	ctx := context.Background()
+	namespaces, err := repo.Namespaces(ctx)
+	require.NoError(t, err)
+	if len(namespaces) == 0 {
+		t.Skip("no namespace to deploy to")
+	}
+	namespace := namespaces[0]
+
+	options, err := json.StructToJsonObject(eve.NamespacePlanOptions{
+		NamespaceRequest: &eve.NamespaceRequest{ID: namespace.ID, Name: namespace.Name, ClusterID: namespace.ClusterID},
+		EnvironmentID:    namespace.EnvironmentID,
+		EnvironmentName:  namespace.EnvironmentName,
+		Type:             eve.DeploymentPlanTypeApplication,
+	})
+	require.NoError(t, err)
+
+	deployment := &data.Deployment{
+		EnvironmentID: namespace.EnvironmentID,
+		NamespaceID:   namespace.ID,
+		PlanOptions:   options,
+		State:         state,
+		User:          "cancel-test",
+	}
+	require.NoError(t, repo.CreateDeployment(ctx, deployment))
+	return deployment
+}
+
+func TestQueue_CancelDeployment(t *testing.T) {
+	ctx := context.Background()
+	db, err := data.GetDBWithTimeout(config.GetDBConfig().DbConnectionString(), 10*time.Second)
+	require.NoError(t, err)
+	repo := data.NewRepo(db)
+
+	newQueue := func(worker *schedulerWorker) *plans.Queue {
+		return plans.NewQueue(worker, repo, crud.NewManager(repo, nil), nil, nil, discardNotifications{}, discardProgress{}, nil)
+	}
+
+	t.Run("scheduled while the cancel was on its way", func(t *testing.T) {
+		deployment := createDeployment(t, repo, data.DeploymentStateQueued)
+		// what scheduleDeployment does once the plan has been sent
+		require.NoError(t, repo.UpdateDeploymentPlanLocation(ctx, deployment.ID, json.Object(`{}`)))
+
+		worker := &schedulerWorker{}
+		cancelled, err := newQueue(worker).CancelDeployment(ctx, deployment.ID.String(), eve.DeploymentCancel{User: "cancel-test"})
+		require.NoError(t, err)
+		require.Equal(t, eve.DeploymentState(data.DeploymentStateCancelled), cancelled.State)
+		require.Len(t, worker.sent, 1)
+		require.Equal(t, queue.CommandCancelNamespace, worker.sent[0].Command)
+	})
+
+	t.Run("the cancel can't be sent to the scheduler", func(t *testing.T) {
+		deployment := createDeployment(t, repo, data.DeploymentStateScheduled)
+
+		worker := &schedulerWorker{sendErr: errors.New("queue unavailable")}
+		cancelled, err := newQueue(worker).CancelDeployment(ctx, deployment.ID.String(), eve.DeploymentCancel{User: "cancel-test"})
+		require.NoError(t, err)
+		require.Equal(t, eve.DeploymentState(data.DeploymentStateCancelled), cancelled.State)
+		require.NotEmpty(t, cancelled.Messages)
+		require.Contains(t, cancelled.Messages[len(cancelled.Messages)-1].Message, "could not be sent to the scheduler")
+
+		stored, err := repo.DeploymentByID(ctx, deployment.ID)
+		require.NoError(t, err)
+		require.Equal(t, data.DeploymentStateCancelled, stored.State)
+
+		messages, err := repo.DeploymentMessagesByDeploymentID(ctx, deployment.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, messages)
+	})
+}
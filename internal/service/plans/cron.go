@@ -32,9 +32,10 @@ type DeploymentCron struct {
 	done    chan bool
 	repo    DeploymentCronRepo
 	dq      DeploymentQueuer
+	events  EventPublisher
 }
 
-func NewDeploymentCron(repo DeploymentCronRepo, dq DeploymentQueuer, timeout time.Duration) *DeploymentCron {
+func NewDeploymentCron(repo DeploymentCronRepo, dq DeploymentQueuer, events EventPublisher, timeout time.Duration) *DeploymentCron {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &DeploymentCron{
 		repo:    repo,
@@ -42,6 +43,7 @@ func NewDeploymentCron(repo DeploymentCronRepo, dq DeploymentQueuer, timeout tim
 		ctx:     ctx,
 		cancel:  cancel,
 		dq:      dq,
+		events:  events,
 		done:    make(chan bool),
 		timeout: timeout,
 	}
@@ -74,6 +76,17 @@ func (dc *DeploymentCron) scheduler(ctx context.Context, job *data.DeploymentCro
 		return nil, errors.Wrap(err)
 	}
 
+	dc.events.Publish(ctx, eve.Event{
+		Type:        eve.EventCronFired,
+		Environment: options.Environment,
+		Artifacts:   definitionArtifactNames(options.Artifacts),
+		Data: eve.DeploymentCronFired{
+			DeploymentCronID: job.ID,
+			Description:      job.Description,
+			DeploymentIDs:    options.DeploymentIDs,
+		},
+	})
+
 	return options.DeploymentIDs, nil
 }
 
@@ -100,8 +113,11 @@ func (dc *DeploymentCron) start() {
 			close(dc.done)
 			return
 		default:
-			ctx, _ := context.WithTimeout(context.WithValue(context.Background(), log.RequestIDKey, log.GetNextRequestID()), dc.timeout)
+			ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), log.RequestIDKey, log.GetNextRequestID()), dc.timeout)
+			now := time.Now()
 			err := dc.run(ctx)
+			statCronDuration.Observe(time.Since(now).Seconds())
+			cancel()
 			if err != nil {
 				dc.log.Error("an error occurred in the deployment cron scheduler", zap.Error(err))
 			}
@@ -0,0 +1,84 @@
+package plans
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+	"go.uber.org/zap"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+	"github.com/unanet/eve/pkg/queue"
+)
+
+// DeadLetter implements queue.DeadLetterSink, messages that have run out of attempts are kept so they can be looked at and replayed
+func (dq *Queue) DeadLetter(ctx context.Context, queueURL string, m *queue.M, cause error) error {
+	deadLetter := data.DeadLetterMessage{
+		Queue:        queueURL,
+		MessageID:    m.MessageID,
+		GroupID:      m.GroupID,
+		Command:      m.Command,
+		Body:         m.Body.String(),
+		Error:        cause.Error(),
+		ReceiveCount: m.ReceiveCount,
+	}
+	deadLetter.EveID.UUID = m.ID
+	deadLetter.EveID.Valid = true
+
+	err := dq.repo.CreateDeadLetterMessage(ctx, &deadLetter)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	dq.Logger(ctx).Warn("queue message dead lettered",
+		zap.Int("dead_letter_id", deadLetter.ID),
+		zap.String("command", m.Command),
+		zap.Any("id", m.ID),
+	)
+	return nil
+}
+
+// ReplayDeadLetter sends a dead lettered message back to the queue it came from
+func (dq *Queue) ReplayDeadLetter(ctx context.Context, id int) (*eve.DeadLetter, error) {
+	deadLetter, err := dq.repo.DeadLetterMessageByID(ctx, id)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	if deadLetter.ReplayedAt.Valid {
+		return nil, errors.BadRequestf("dead letter: %d, has already been replayed", id)
+	}
+
+	err = dq.repo.UpdateDeadLetterMessageReplayed(ctx, id, true)
+	if err != nil {
+		if _, ok := err.(data.NotFoundError); ok {
+			return nil, errors.BadRequestf("dead letter: %d, has already been replayed", id)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	err = dq.worker.Message(ctx, deadLetter.Queue, &queue.M{
+		ID:       deadLetter.EveID.UUID,
+		GroupID:  deadLetter.GroupID,
+		Body:     json.Object(deadLetter.Body),
+		Command:  deadLetter.Command,
+		DedupeID: fmt.Sprintf("replay-%d", deadLetter.ID),
+	})
+	if err != nil {
+		if uErr := dq.repo.UpdateDeadLetterMessageReplayed(ctx, id, false); uErr != nil {
+			dq.Logger(ctx).Error("failed to reset replayed dead letter", zap.Error(uErr))
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	deadLetter, err = dq.repo.DeadLetterMessageByID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	result := eve.ToDeadLetter(*deadLetter)
+	return &result, nil
+}
@@ -0,0 +1,93 @@
+// +build local
+
+package plans_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/unanet/eve/internal/config"
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service/crud"
+	"github.com/unanet/eve/internal/service/plans"
+	"github.com/unanet/eve/pkg/queue"
+)
+
+// memoryBackend hands every message it's sent to the next Receive and counts how often it's been received
+type memoryBackend struct {
+	mutex    sync.Mutex
+	messages []*queue.M
+}
+
+func (b *memoryBackend) Message(ctx context.Context, m *queue.M) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.messages = append(b.messages, m.WithContext(ctx))
+	return nil
+}
+
+func (b *memoryBackend) Receive(ctx context.Context) ([]*queue.M, error) {
+	b.mutex.Lock()
+	ms := b.messages
+	b.messages = nil
+	b.mutex.Unlock()
+	if len(ms) == 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	for _, m := range ms {
+		m.ReceiveCount++
+	}
+	return ms, nil
+}
+
+func (b *memoryBackend) Delete(ctx context.Context, m *queue.M) error {
+	return nil
+}
+
+func (b *memoryBackend) ChangeVisibility(ctx context.Context, m *queue.M, timeout time.Duration) error {
+	return b.Message(m.Context(), m)
+}
+
+func (b *memoryBackend) URL() string {
+	return "memory"
+}
+
+func (b *memoryBackend) Queue(qUrl string) queue.Backend {
+	return b
+}
+
+func TestQueue_FailedScheduleIsDeadLettered(t *testing.T) {
+	ctx := context.Background()
+	db, err := data.GetDBWithTimeout(config.GetDBConfig().DbConnectionString(), 10*time.Second)
+	require.NoError(t, err)
+	repo := data.NewRepo(db)
+
+	backend := &memoryBackend{}
+	worker := queue.NewWorker("dead-letter-test", backend, 5*time.Second)
+	dq := plans.NewQueue(worker, repo, crud.NewManager(repo, nil), nil, nil, nil, nil, nil)
+	worker.SetRetryPolicy(queue.RetryPolicy{MaxAttempts: 2}, dq)
+	dq.Start()
+	defer dq.Stop()
+
+	// there's no deployment with the id, so scheduling it fails every time
+	id := uuid.NewV4()
+	require.NoError(t, backend.Message(ctx, &queue.M{
+		ID:      id,
+		GroupID: "dead-letter-test",
+		Command: queue.CommandScheduleDeployment,
+	}))
+
+	require.Eventually(t, func() bool {
+		deadLetters, err := repo.DeadLetterMessages(ctx, 10, 0, data.Where("eve_id", id))
+		require.NoError(t, err)
+		return len(deadLetters) == 1 && deadLetters[0].ReceiveCount == 2
+	}, 5*time.Second, 50*time.Millisecond)
+}
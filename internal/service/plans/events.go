@@ -0,0 +1,57 @@
+package plans
+
+import (
+	"context"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+// EventPublisher sends an event to the subscriptions that match it
+type EventPublisher interface {
+	Publish(ctx context.Context, event eve.Event)
+}
+
+func deploymentEvent(eventType eve.EventType, deploymentID uuid.UUID, environment string, namespace *eve.NamespaceRequest, artifacts eve.StringList, data interface{}) eve.Event {
+	event := eve.Event{
+		Type:         eventType,
+		Environment:  environment,
+		Artifacts:    artifacts,
+		DeploymentID: &deploymentID,
+		Data:         data,
+	}
+	if namespace != nil {
+		event.Namespace = namespace.Name
+	}
+	return event
+}
+
+func definitionArtifactNames(artifacts eve.ArtifactDefinitions) eve.StringList {
+	var names eve.StringList
+	for _, x := range artifacts {
+		name := x.ArtifactName
+		if len(name) == 0 {
+			name = x.Name
+		}
+		if !names.Contains(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func planArtifactNames(plan *eve.NSDeploymentPlan) eve.StringList {
+	var names eve.StringList
+	for _, x := range plan.Services {
+		if !names.Contains(x.ArtifactName) {
+			names = append(names, x.ArtifactName)
+		}
+	}
+	for _, x := range plan.Jobs {
+		if !names.Contains(x.ArtifactName) {
+			names = append(names, x.ArtifactName)
+		}
+	}
+	return names
+}
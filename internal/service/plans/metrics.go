@@ -0,0 +1,76 @@
+package plans
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+var (
+	statDeploymentTimedOutCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eve_deployment_timed_out_total",
+			Help: "The total number of deployments that never received a reply from the scheduler",
+		}, []string{"environment", "namespace"})
+
+	statDeploymentOutcomeCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eve_deployment_outcome_total",
+			Help: "The total number of finished deployments by the state they finished in",
+		}, []string{"environment", "namespace", "state"})
+
+	statDeploymentScheduleLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "eve_deployment_schedule_latency_seconds",
+			Help:    "Time from a deployment being created to its plan being sent to the scheduler",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+		}, []string{"environment"})
+
+	statDeploymentDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "eve_deployment_duration_seconds",
+			Help:    "Time from a deployment being created to it finishing",
+			Buckets: prometheus.ExponentialBuckets(5, 2, 12),
+		}, []string{"environment", "state"})
+
+	statDeploymentRunDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "eve_deployment_run_duration_seconds",
+			Help:    "Time from a deployment's plan being sent to the scheduler to it finishing",
+			Buckets: prometheus.ExponentialBuckets(5, 2, 12),
+		}, []string{"environment", "state"})
+
+	statCallbackCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eve_callback_total",
+			Help: "The total number of callbacks posted by result",
+		}, []string{"result"})
+
+	statCronDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name: "eve_deployment_cron_duration_seconds",
+			Help: "Time it takes to run the deployment cron",
+		})
+)
+
+func observeDeploymentScheduled(deployment *data.Deployment, options eve.NamespacePlanOptions) {
+	if !deployment.CreatedAt.Valid {
+		return
+	}
+	statDeploymentScheduleLatency.WithLabelValues(options.EnvironmentName).Observe(time.Since(deployment.CreatedAt.Time).Seconds())
+}
+
+func observeDeploymentFinished(deployment *data.Deployment, environment string, namespace string) {
+	statDeploymentOutcomeCount.WithLabelValues(environment, namespace, string(deployment.State)).Inc()
+	if deployment.CreatedAt.Valid {
+		statDeploymentDuration.WithLabelValues(environment, string(deployment.State)).Observe(time.Since(deployment.CreatedAt.Time).Seconds())
+	}
+	// a deployment that never reached the scheduler (dry run, nothing to deploy, cancelled while queued) has no run
+	if deployment.ScheduledAt.Valid {
+		statDeploymentRunDuration.WithLabelValues(environment, string(deployment.State)).Observe(time.Since(deployment.ScheduledAt.Time).Seconds())
+	}
+}
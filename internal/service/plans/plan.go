@@ -23,16 +23,18 @@ type VersionQuery interface {
 }
 
 type PlanGenerator struct {
-	repo *data.Repo
-	vq   VersionQuery
-	q    QWriter
+	repo   *data.Repo
+	vq     VersionQuery
+	q      QWriter
+	events EventPublisher
 }
 
-func NewPlanGenerator(r *data.Repo, v VersionQuery, q QWriter) *PlanGenerator {
+func NewPlanGenerator(r *data.Repo, v VersionQuery, q QWriter, events EventPublisher) *PlanGenerator {
 	return &PlanGenerator{
-		repo: r,
-		vq:   v,
-		q:    q,
+		repo:   r,
+		vq:     v,
+		q:      q,
+		events: events,
 	}
 }
 
@@ -95,6 +97,9 @@ func (d *PlanGenerator) QueuePlan(ctx context.Context, options *eve.DeploymentPl
 			PlanOptions:   nsPlanOptions,
 			User:          options.User,
 		}
+		if options.IsRollback() {
+			dataDeployment.RollbackOf = uuid.NullUUID{UUID: *options.RollbackOf, Valid: true}
+		}
 		repoErr := d.repo.CreateDeployment(ctx, &dataDeployment)
 		if repoErr != nil {
 			return errors.Wrap(repoErr)
@@ -112,6 +117,8 @@ func (d *PlanGenerator) QueuePlan(ctx context.Context, options *eve.DeploymentPl
 		if repoErr != nil {
 			return errors.Wrap(repoErr)
 		}
+		d.events.Publish(ctx, deploymentEvent(eve.EventDeploymentQueued, dataDeployment.ID, env.Name, ns,
+			definitionArtifactNames(options.Artifacts), eve.ToDeployment(dataDeployment)))
 	}
 	return nil
 }
@@ -237,15 +244,24 @@ func (d *PlanGenerator) setArtifactoryVersions(ctx context.Context, options *eve
 	var artifacts eve.ArtifactDefinitions
 	for _, a := range options.Artifacts {
 		// if you didn't pass a full version, we need to add a wildcard so it work correctly to query artifactory
+		requestedVersion := a.ArtifactoryRequestedVersion()
+		// a rollback or restart pins the recorded version, a wildcard could resolve to a newer build of it
+		if options.PinsVersions() && len(a.RequestedVersion) > 0 {
+			requestedVersion = a.RequestedVersion
+		}
 		log.Logger.Info("get artifact",
 			zap.String("feed", a.ArtifactoryFeed),
 			zap.String("path", a.ArtifactoryPath),
-			zap.String("version", a.ArtifactoryRequestedVersion()),
+			zap.String("version", requestedVersion),
 		)
-		version, err := d.vq.GetLatestVersion(ctx, a.ArtifactoryFeed, a.ArtifactoryPath, a.ArtifactoryRequestedVersion())
+		version, err := d.vq.GetLatestVersion(ctx, a.ArtifactoryFeed, a.ArtifactoryPath, requestedVersion)
 		if err != nil {
 			if _, ok := err.(artifactory.NotFoundError); ok {
-				options.Message("artifact not found in artifactory: %s/%s/%s:%s", a.ArtifactoryFeed, a.ArtifactoryPath, a.Name, a.ArtifactoryRequestedVersion())
+				// a rollback has to put back exactly what was there before, so we refuse instead of skipping the artifact
+				if options.IsRollback() {
+					return errors.NotFoundf("rollback artifact no longer available in artifactory: %s/%s/%s:%s", a.ArtifactoryFeed, a.ArtifactoryPath, a.Name, requestedVersion)
+				}
+				options.Message("artifact not found in artifactory: %s/%s/%s:%s", a.ArtifactoryFeed, a.ArtifactoryPath, a.Name, requestedVersion)
 				continue
 			}
 			return errors.Wrap(err)
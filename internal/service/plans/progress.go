@@ -0,0 +1,80 @@
+package plans
+
+import (
+	"context"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+// ProgressPublisher streams deployment progress to anyone watching the deployment or its environment
+type ProgressPublisher interface {
+	Publish(ctx context.Context, p eve.DeploymentProgress)
+}
+
+func deploymentProgress(progressType eve.DeploymentProgressType, deploymentID uuid.UUID, environment string, namespace *eve.NamespaceRequest) eve.DeploymentProgress {
+	p := eve.DeploymentProgress{
+		Type:         progressType,
+		DeploymentID: deploymentID,
+		Environment:  environment,
+	}
+	if namespace != nil {
+		p.Namespace = namespace.Name
+	}
+	return p
+}
+
+func deployResult(name string, serviceID int, jobID int, a *eve.DeployArtifact) eve.DeploymentResult {
+	result := eve.DeploymentResult{
+		ServiceID: serviceID,
+		JobID:     jobID,
+		Name:      name,
+	}
+	if a != nil {
+		result.RequestedVersion = a.RequestedVersion
+		result.DeployedVersion = a.DeployedVersion
+		result.AvailableVersion = a.AvailableVersion
+		result.Result = a.Result
+		result.ExitCode = a.ExitCode
+	}
+	return result
+}
+
+func planResults(plan *eve.NSDeploymentPlan) []eve.DeploymentResult {
+	var results []eve.DeploymentResult
+	for _, x := range plan.Services {
+		results = append(results, deployResult(x.ServiceName, x.ServiceID, 0, x.DeployArtifact))
+	}
+	for _, x := range plan.Jobs {
+		results = append(results, deployResult(x.JobName, 0, x.JobID, x.DeployArtifact))
+	}
+	return results
+}
+
+// publishPlan lets watchers know what's about to be deployed
+func (dq *Queue) publishPlan(ctx context.Context, plan *eve.NSDeploymentPlan) {
+	p := deploymentProgress(eve.DeploymentProgressPlan, plan.DeploymentID, plan.EnvironmentName, plan.Namespace)
+	p.Artifacts = planResults(plan)
+	p.Messages = plan.Messages
+	dq.progress.Publish(ctx, p)
+}
+
+// publishResults sends an event per service and job, and then the completion when the deployment finished
+func (dq *Queue) publishResults(ctx context.Context, deploymentID uuid.UUID, plan *eve.NSDeploymentPlan, finished bool) {
+	for _, x := range planResults(plan) {
+		result := x
+		p := deploymentProgress(eve.DeploymentProgressResult, deploymentID, plan.EnvironmentName, plan.Namespace)
+		p.Result = &result
+		dq.progress.Publish(ctx, p)
+	}
+
+	if finished {
+		return
+	}
+
+	p := deploymentProgress(eve.DeploymentProgressComplete, deploymentID, plan.EnvironmentName, plan.Namespace)
+	p.State = plan.State
+	p.Messages = plan.Messages
+	dq.progress.Publish(ctx, p)
+}
@@ -2,7 +2,9 @@ package plans
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/unanet/eve/internal/data"
 	"github.com/unanet/eve/internal/service/crud"
 	"github.com/unanet/eve/pkg/eve"
+	"github.com/unanet/eve/pkg/notify"
 	"github.com/unanet/eve/pkg/queue"
 	"github.com/unanet/go/pkg/errors"
 	"go.uber.org/zap"
@@ -19,6 +22,10 @@ type QWriter interface {
 	Message(ctx context.Context, m *queue.M) error
 }
 
+type DeploymentNotifier interface {
+	Notify(ctx context.Context, notification notify.Notification)
+}
+
 type QueueWorker interface {
 	Start(queue.Handler)
 	Stop()
@@ -27,10 +34,6 @@ type QueueWorker interface {
 	Message(ctx context.Context, qUrl string, m *queue.M) error
 }
 
-type HttpCallback interface {
-	Post(ctx context.Context, url string, body interface{}) error
-}
-
 func fromDataService(s data.DeployService) *eve.DeployService {
 	return &eve.DeployService{
 		ServiceID:        s.ServiceID,
@@ -86,13 +89,20 @@ func fromDataJobs(d data.DeployJobs) eve.DeployJobs {
 
 type messageLogger func(format string, a ...interface{})
 
+// SecretResolver replaces the secret references in metadata, returning the JSON pointers of the values it replaced
+type SecretResolver interface {
+	Resolve(ctx context.Context, metadata eve.MetadataField) (eve.MetadataField, []string, error)
+}
+
 type Queue struct {
 	worker     QueueWorker
 	repo       *data.Repo
 	uploader   eve.CloudUploader
-	callback   HttpCallback
 	downloader eve.CloudDownloader
 	crud       *crud.Manager
+	notifier   DeploymentNotifier
+	progress   ProgressPublisher
+	secrets    SecretResolver
 }
 
 func NewQueue(
@@ -101,14 +111,18 @@ func NewQueue(
 	crud *crud.Manager,
 	uploader eve.CloudUploader,
 	downloader eve.CloudDownloader,
-	httpCallBack HttpCallback) *Queue {
+	notifier DeploymentNotifier,
+	progress ProgressPublisher,
+	secrets SecretResolver) *Queue {
 	return &Queue{
 		worker:     worker,
 		repo:       repo,
 		crud:       crud,
 		uploader:   uploader,
 		downloader: downloader,
-		callback:   httpCallBack,
+		notifier:   notifier,
+		progress:   progress,
+		secrets:    secrets,
 	}
 }
 
@@ -181,6 +195,18 @@ func (dq *Queue) setupNSDeploymentPlan(ctx context.Context, deploymentID uuid.UU
 	return &plan, nil
 }
 
+// setMetadata sets the artifact metadata with its secret references resolved, along with the JSON pointers of the
+// secrets so they can be masked in the stored plan and everywhere else it's shown
+func (dq *Queue) setMetadata(ctx context.Context, artifact *eve.DeployArtifact, metadata eve.MetadataField) error {
+	resolved, pointers, err := dq.secrets.Resolve(ctx, metadata)
+	if err != nil {
+		return err
+	}
+	artifact.Metadata = resolved
+	artifact.Secrets = pointers
+	return nil
+}
+
 func (dq *Queue) createServicesDeployment(ctx context.Context, deploymentID uuid.UUID, options eve.NamespacePlanOptions) (*eve.NSDeploymentPlan, error) {
 	nSDeploymentPlan, err := dq.setupNSDeploymentPlan(ctx, deploymentID, options)
 	if err != nil {
@@ -192,11 +218,14 @@ func (dq *Queue) createServicesDeployment(ctx context.Context, deploymentID uuid
 	}
 	services := fromDataServices(dataServices)
 	for _, x := range services {
-		metadata, err := dq.crud.ServiceMetadata(ctx, x.ServiceID)
+		metadata, err := dq.crud.DecryptedServiceMetadata(ctx, x.ServiceID)
 		if err != nil {
 			return nil, errors.Wrap(err)
 		}
-		x.Metadata = metadata
+		err = dq.setMetadata(ctx, x.DeployArtifact, metadata)
+		if err != nil {
+			return nil, errors.Wrapf("service: %s, %s", x.ServiceName, err)
+		}
 
 		definitions, err := dq.crud.ServiceDefinitionResults(ctx, x.ServiceID)
 		if err != nil {
@@ -213,10 +242,15 @@ func (dq *Queue) createServicesDeployment(ctx context.Context, deploymentID uuid
 		dq.matchArtifact(x.DeployArtifact, x.ServiceName, options, nSDeploymentPlan.Message)
 	}
 	// Trap the restart command, since we don't care about matching a service (we just want to restart whatever version is currently deployed)
-	if options.ArtifactsSupplied && options.Type != eve.DeploymentPlanTypeRestart {
+	// unless the restart was scoped to named services, in which case we want to know which of them weren't restarted
+	if options.ArtifactsSupplied {
 		unmatched := options.Artifacts.UnMatched()
 		for _, x := range unmatched {
-			nSDeploymentPlan.Message("unmatched service: %s:%s", x.Name, x.AvailableVersion)
+			if options.Type != eve.DeploymentPlanTypeRestart {
+				nSDeploymentPlan.Message("unmatched service: %s:%s", x.Name, x.AvailableVersion)
+			} else if len(x.Name) > 0 {
+				nSDeploymentPlan.Message("service: %s, was not restarted", x.Name)
+			}
 		}
 	}
 	nSDeploymentPlan.Services = services.ToDeploy()
@@ -235,11 +269,14 @@ func (dq *Queue) createJobsDeployment(ctx context.Context, deploymentID uuid.UUI
 	}
 	jobs := fromDataJobs(dataJobs)
 	for _, x := range jobs {
-		metadata, mErr := dq.crud.JobMetadata(ctx, x.JobID)
+		metadata, mErr := dq.crud.DecryptedJobMetadata(ctx, x.JobID)
 		if mErr != nil {
 			return nil, errors.Wrap(mErr)
 		}
-		x.Metadata = metadata
+		mErr = dq.setMetadata(ctx, x.DeployArtifact, metadata)
+		if mErr != nil {
+			return nil, errors.Wrapf("job: %s, %s", x.JobName, mErr)
+		}
 
 		definition, dErr := dq.crud.JobDefinitionResults(ctx, x.JobID)
 		if dErr != nil {
@@ -262,24 +299,31 @@ func (dq *Queue) createJobsDeployment(ctx context.Context, deploymentID uuid.UUI
 	return nSDeploymentPlan, nil
 }
 
-func (dq *Queue) rollbackError(ctx context.Context, m *queue.M, err error) error {
-	qerr := dq.worker.DeleteMessage(ctx, m)
-	if qerr != nil {
-		dq.Logger(ctx).Error("an error occurred while trying to remove the message due to an error", zap.Any("queue_message", m), zap.Error(qerr))
-	}
-	return errors.Wrap(err)
-}
-
+// scheduleDeployment leaves the message on the queue when it fails, the worker's retry policy decides when it's
+// retried and when it's dead lettered. A deployment that's never scheduled is timed out by the DeploymentReaper
 func (dq *Queue) scheduleDeployment(ctx context.Context, m *queue.M) error {
 	deployment, err := dq.repo.UpdateDeploymentReceiptHandle(ctx, m.ID, m.ReceiptHandle)
 	if err != nil {
-		return dq.rollbackError(ctx, m, err)
+		return errors.Wrap(err)
+	}
+
+	if deployment.State.Terminal() {
+		dq.Logger(ctx).Info("deployment finished before it was scheduled, removing message", zap.Any("id", deployment.ID), zap.String("state", string(deployment.State)))
+		return dq.worker.DeleteMessage(ctx, m)
+	}
+
+	// a redelivered message (the visibility timeout expired while the scheduler still has the plan) must not send a
+	// second plan, only a queued deployment is scheduled. The message is left in flight with its new receipt handle
+	// so the update can still remove it
+	if deployment.State != data.DeploymentStateQueued {
+		dq.Logger(ctx).Info("deployment already scheduled, ignoring redelivered message", zap.Any("id", deployment.ID), zap.String("state", string(deployment.State)))
+		return nil
 	}
 
 	var options eve.NamespacePlanOptions
 	err = json.Unmarshal(deployment.PlanOptions, &options)
 	if err != nil {
-		return dq.rollbackError(ctx, m, err)
+		return errors.Wrap(err)
 	}
 
 	var nsDeploymentPlan *eve.NSDeploymentPlan
@@ -290,52 +334,72 @@ func (dq *Queue) scheduleDeployment(ctx context.Context, m *queue.M) error {
 		nsDeploymentPlan, err = dq.createJobsDeployment(ctx, deployment.ID, options)
 	}
 	if err != nil {
-		return dq.rollbackError(ctx, m, err)
+		return errors.Wrap(err)
 	}
 
 	if len(options.CallbackURL) > 0 {
-		if cErr := dq.callback.Post(ctx, options.CallbackURL, nsDeploymentPlan); cErr != nil {
-			dq.Logger(ctx).Warn("schedule deployment callback failed",
-				zap.Error(cErr),
-				zap.String("plan_callback_url", nsDeploymentPlan.CallbackURL),
-				zap.String("options_callback_url", options.CallbackURL),
-			)
-		}
+		dq.queueCallback(ctx, deployment.ID, options.CallbackURL, nsDeploymentPlan.Masked())
 	}
 
 	if options.DryRun || nsDeploymentPlan.NothingToDeploy() {
 		dq.Logger(ctx).Info("message deleted")
 		err = dq.worker.DeleteMessage(ctx, m)
 		if err != nil {
-			return dq.rollbackError(ctx, m, err)
+			return errors.Wrap(err)
 		}
 		dq.Logger(ctx).Info("updating scheduled deployment", zap.Any("id", deployment.ID))
-		_, err = dq.repo.UpdateDeploymentResult(ctx, deployment.ID)
+		deployment, err = dq.repo.UpdateDeploymentResult(ctx, deployment.ID, data.DeploymentStateCompleted)
 		if err != nil {
 			return errors.Wrap(err)
 		}
+		observeDeploymentFinished(deployment, options.EnvironmentName, nsDeploymentPlan.Namespace.Name)
+		nsDeploymentPlan.State = eve.DeploymentStateCompleted
+		dq.publishPlan(ctx, nsDeploymentPlan)
+		dq.publishResults(ctx, deployment.ID, nsDeploymentPlan, false)
+		dq.crud.Publish(ctx, deploymentEvent(eve.EventDeploymentCompleted, deployment.ID, options.EnvironmentName,
+			nsDeploymentPlan.Namespace, planArtifactNames(nsDeploymentPlan), nsDeploymentPlan.Masked()))
 		return nil
 	}
 
+	nsDeploymentPlan.TraceContext = queue.InjectTraceContext(ctx)
 	mBody, err := eve.MarshalNSDeploymentPlanToS3LocationBody(ctx, dq.uploader, nsDeploymentPlan)
 	if err != nil {
 		return errors.Wrap(err)
 	}
+	// the resolved secrets are only in the message, the stored plan and its location have them masked
+	schBody, err := eve.PlanMessageBody(mBody, nsDeploymentPlan)
+	if err != nil {
+		return errors.Wrap(err)
+	}
 
 	err = dq.worker.Message(ctx, nsDeploymentPlan.SchQueueUrl, &queue.M{
-		ID:      deployment.ID,
-		GroupID: nsDeploymentPlan.Namespace.GetQueueGroupID(),
-		Body:    mBody,
-		Command: nsDeploymentPlan.Type.Command(),
+		ID:       deployment.ID,
+		GroupID:  nsDeploymentPlan.Namespace.GetQueueGroupID(),
+		Body:     schBody,
+		Command:  nsDeploymentPlan.Type.Command(),
+		DedupeID: fmt.Sprintf("schedule-%s", deployment.ID),
 	})
 	if err != nil {
-		return dq.rollbackError(ctx, m, err)
+		return errors.Wrap(err)
 	}
 
 	err = dq.repo.UpdateDeploymentPlanLocation(ctx, deployment.ID, mBody)
 	if err != nil {
-		return dq.rollbackError(ctx, m, err)
+		if _, ok := err.(data.NotFoundError); ok {
+			// it was cancelled (or timed out) while the plan was being sent, the scheduler already has the plan so
+			// it needs the cancel as well
+			deployment.PlanLocation = mBody
+			if err = dq.cancelScheduled(ctx, deployment, options); err != nil {
+				return errors.Wrap(err)
+			}
+			return dq.worker.DeleteMessage(ctx, m)
+		}
+		return errors.Wrap(err)
 	}
+	observeDeploymentScheduled(deployment, options)
+	dq.publishPlan(ctx, nsDeploymentPlan)
+	dq.crud.Publish(ctx, deploymentEvent(eve.EventDeploymentScheduled, deployment.ID, options.EnvironmentName,
+		nsDeploymentPlan.Namespace, planArtifactNames(nsDeploymentPlan), nsDeploymentPlan.Masked()))
 
 	return nil
 }
@@ -359,56 +423,80 @@ func (dq *Queue) handleMessage(ctx context.Context, m *queue.M) error {
 
 func (dq *Queue) updateDeployment(ctx context.Context, m *queue.M) error {
 	dq.Logger(ctx).Info("updating message deployment", zap.Any("id", m.ID))
-	deployment, err := dq.repo.UpdateDeploymentResult(ctx, m.ID)
+	plan, err := eve.UnMarshalNSDeploymentFromS3LocationBody(ctx, dq.downloader, m.Body)
 	if err != nil {
 		return errors.Wrap(err)
 	}
 
-	plan, err := eve.UnMarshalNSDeploymentFromS3LocationBody(ctx, dq.downloader, m.Body)
+	plan.State = plan.Outcome()
+	finished, redelivered := false, false
+	deployment, err := dq.repo.UpdateDeploymentResult(ctx, m.ID, eve.ToDataDeploymentState(plan.State))
 	if err != nil {
-		return errors.Wrap(err)
+		if _, ok := err.(data.NotFoundError); !ok {
+			return errors.Wrap(err)
+		}
+		// the deployment was already finished (cancelled/timed out), we still want what the scheduler did recorded
+		deployment, err = dq.repo.DeploymentByID(ctx, m.ID)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		finished = true
+		// anything but a cancel or timeout means this update was already handled and the message was redelivered
+		redelivered = deployment.State != data.DeploymentStateCancelled && deployment.State != data.DeploymentStateTimedOut
+		plan.State = eve.ParseDeploymentState(deployment.State)
+	} else {
+		observeDeploymentFinished(deployment, plan.EnvironmentName, plan.Namespace.Name)
 	}
 
 	for _, x := range plan.Services {
-		if x.Result != eve.DeployArtifactResultSuccess {
-			continue
-		}
-
-		err = dq.repo.UpdateDeployedServiceVersion(ctx, x.ServiceID, x.AvailableVersion)
+		artifact := toDataDeploymentArtifact(deployment.ID, x.DeployArtifact)
+		artifact.ServiceID = sql.NullInt32{Int32: int32(x.ServiceID), Valid: true}
+		err = dq.repo.RecordDeployResult(ctx, artifact, x.Result == eve.DeployArtifactResultSuccess)
 		if err != nil {
 			return errors.Wrap(err)
 		}
 	}
 
 	for _, x := range plan.Jobs {
-		if x.Result != eve.DeployArtifactResultSuccess {
-			continue
-		}
-
-		err = dq.repo.UpdateDeployedJobVersion(ctx, x.JobID, x.AvailableVersion)
+		artifact := toDataDeploymentArtifact(deployment.ID, x.DeployArtifact)
+		artifact.JobID = sql.NullInt32{Int32: int32(x.JobID), Valid: true}
+		err = dq.repo.RecordDeployResult(ctx, artifact, x.Result == eve.DeployArtifactResultSuccess)
 		if err != nil {
 			return errors.Wrap(err)
 		}
 	}
 
-	if len(plan.CallbackURL) > 0 {
-		if cErr := dq.callback.Post(ctx, plan.CallbackURL, plan); cErr != nil {
-			dq.Logger(ctx).Warn("update deployment callback failed",
-				zap.Error(cErr),
-				zap.String("plan_callback_url", plan.CallbackURL),
-			)
+	err = dq.repo.CreateDeploymentMessages(ctx, deployment.ID, data.DeploymentMessageSourcePlan, plan.Messages)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	// the first delivery already called back and published the results
+	if !redelivered {
+		if len(plan.CallbackURL) > 0 {
+			dq.queueCallback(ctx, deployment.ID, plan.CallbackURL, plan.Masked())
 		}
+		dq.publishResults(ctx, deployment.ID, plan, finished)
+	}
+
+	// a deployment that was already finished by eve sent its event when it was cancelled or timed out
+	if !finished {
+		dq.crud.Publish(ctx, deploymentEvent(eve.DeploymentEventType(plan.State), deployment.ID, plan.EnvironmentName,
+			plan.Namespace, planArtifactNames(plan), plan.Masked()))
+		dq.notifier.Notify(ctx, notify.PlanNotification(plan))
 	}
 
 	// Here we are deleting the original deploy message which unblocks deployments for a namespace in an environment
 	// We will need to add some additional logic to this to account for certain scenarios where we should
 	// Still Delete the Message that triggers this updateDeployment (like an error that returns not found or already deleted)
-	err = dq.worker.DeleteMessage(ctx, &queue.M{
-		ID:            deployment.ID,
-		ReceiptHandle: deployment.ReceiptHandle.String,
-	})
-	if err != nil {
-		return errors.Wrap(err)
+	if !finished {
+		err = dq.worker.DeleteMessage(ctx, &queue.M{
+			ID:            deployment.ID,
+			ReceiptHandle: deployment.ReceiptHandle.String,
+		})
+		if err != nil {
+			return errors.Wrap(err)
+		}
 	}
 
 	err = dq.worker.DeleteMessage(ctx, m)
@@ -440,8 +528,8 @@ func (dq *Queue) callbackMessage(ctx context.Context, m *queue.M) error {
 		return nil
 	}
 
-	if d.State == data.DeploymentStateCompleted {
-		dq.Logger(ctx).Warn("message callback came in for a deployment that's already completed, skipping...", zap.String("id", d.ID.String()))
+	if d.State.Terminal() {
+		dq.Logger(ctx).Warn("message callback came in for a deployment that's already finished, skipping...", zap.String("id", d.ID.String()), zap.String("state", string(d.State)))
 		return nil
 	}
 	var options eve.NamespacePlanOptions
@@ -450,19 +538,88 @@ func (dq *Queue) callbackMessage(ctx context.Context, m *queue.M) error {
 		return errors.Wrap(err)
 	}
 
+	err = dq.repo.CreateDeploymentMessages(ctx, d.ID, data.DeploymentMessageSourceCallback, cm.Messages)
+	if err != nil {
+		dq.Logger(ctx).Warn("failed to store the callback messages", zap.String("id", d.ID.String()), zap.Error(errors.Wrap(err)))
+	}
+
 	dcm := eve.DeploymentCallbackMessage{
 		DeploymentID: m.ID,
 		Status:       eve.DeploymentPlanStatusMessage,
+		State:        eve.ParseDeploymentState(d.State),
 		Type:         options.Type,
 		Messages:     cm.Messages,
 	}
 
+	progress := deploymentProgress(eve.DeploymentProgressMessage, d.ID, options.EnvironmentName, options.NamespaceRequest)
+	progress.State = dcm.State
+	progress.Messages = cm.Messages
+	dq.progress.Publish(ctx, progress)
+
 	if len(options.CallbackURL) > 0 {
-		if cErr := dq.callback.Post(ctx, options.CallbackURL, dcm); cErr != nil {
-			dq.Logger(ctx).Warn("callback message callback failed", zap.String("callback_url", options.CallbackURL), zap.Error(cErr), zap.String("id", d.ID.String()))
-		}
+		dq.queueCallback(ctx, d.ID, options.CallbackURL, dcm)
 	} else {
 		dq.Logger(ctx).Warn("message callback came in for a deployment without a registered callback, skipping...", zap.String("id", d.ID.String()))
 	}
 	return nil
 }
+
+func toDataDeploymentArtifact(deploymentID uuid.UUID, a *eve.DeployArtifact) *data.DeploymentArtifact {
+	return &data.DeploymentArtifact{
+		DeploymentID:     deploymentID,
+		ToVersion:        a.AvailableVersion,
+		RequestedVersion: sql.NullString{String: a.RequestedVersion, Valid: len(a.RequestedVersion) > 0},
+		DeployedVersion:  sql.NullString{String: a.DeployedVersion, Valid: len(a.DeployedVersion) > 0},
+		Result:           a.Result.String(),
+		ExitCode:         a.ExitCode,
+	}
+}
+
+// releaseDeployment cleans up after a deployment that was finished by eve instead of the scheduler (cancelled, timed out).
+// The original api queue message is removed so the namespace group is unblocked and the callback is told why.
+// Failures are only logged since the deployment state has already been changed at this point
+func (dq *Queue) releaseDeployment(ctx context.Context, deployment *data.Deployment, options eve.NamespacePlanOptions, source string, message string) {
+	observeDeploymentFinished(deployment, options.EnvironmentName, options.NamespaceRequest.Name)
+
+	err := dq.repo.CreateDeploymentMessages(ctx, deployment.ID, source, []string{message})
+	if err != nil {
+		dq.Logger(ctx).Warn("failed to store the deployment message", zap.String("id", deployment.ID.String()), zap.Error(err))
+	}
+
+	// Without a receipt handle the message hasn't been picked up yet, scheduleDeployment removes it when it sees the state
+	if deployment.ReceiptHandle.Valid {
+		err = dq.worker.DeleteMessage(ctx, &queue.M{
+			ID:            deployment.ID,
+			ReceiptHandle: deployment.ReceiptHandle.String,
+		})
+		if err != nil {
+			dq.Logger(ctx).Warn("failed to remove the deployment message", zap.String("id", deployment.ID.String()), zap.Error(err))
+		}
+	}
+
+	dcm := eve.DeploymentCallbackMessage{
+		DeploymentID: deployment.ID,
+		Status:       eve.DeploymentPlanStatusComplete,
+		State:        eve.ParseDeploymentState(deployment.State),
+		Type:         options.Type,
+		Messages:     []string{message},
+	}
+
+	if len(options.CallbackURL) > 0 {
+		dq.queueCallback(ctx, deployment.ID, options.CallbackURL, dcm)
+	}
+
+	dq.crud.Publish(ctx, deploymentEvent(eve.DeploymentEventType(dcm.State), deployment.ID, options.EnvironmentName,
+		options.NamespaceRequest, definitionArtifactNames(options.Artifacts), dcm))
+
+	progress := deploymentProgress(eve.DeploymentProgressComplete, deployment.ID, options.EnvironmentName, options.NamespaceRequest)
+	progress.State = dcm.State
+	progress.Messages = dcm.Messages
+	dq.progress.Publish(ctx, progress)
+
+	var namespace string
+	if options.NamespaceRequest != nil {
+		namespace = options.NamespaceRequest.Name
+	}
+	dq.notifier.Notify(ctx, notify.CallbackNotification(dcm, options.EnvironmentName, namespace))
+}
@@ -0,0 +1,132 @@
+package plans
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/log"
+	"go.uber.org/zap"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+type DeploymentReaperRepo interface {
+	StuckDeployments(ctx context.Context, before time.Time) ([]data.Deployment, error)
+}
+
+type DeploymentTimer interface {
+	TimeoutDeployment(ctx context.Context, deployment *data.Deployment, deadline time.Duration) error
+}
+
+// DeploymentReaper times out scheduled deployments that never get a reply from the scheduler, and queued ones that
+// never get scheduled, otherwise the namespace queue group and any deployment cron waiting on them would stay blocked
+//
+// The deadline (DEPLOYMENT_DEADLINE, 2h by default) can be longer than the api queue's visibility timeout
+// (API_Q_VISIBILITY_TIMEOUT, 1h by default). The deploy message of a scheduled deployment is then redelivered before
+// the deadline, scheduleDeployment ignores it and keeps its new receipt handle, so the release still removes it
+type DeploymentReaper struct {
+	log      *zap.Logger
+	timeout  time.Duration
+	deadline time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan bool
+	repo     DeploymentReaperRepo
+	dt       DeploymentTimer
+}
+
+func NewDeploymentReaper(repo DeploymentReaperRepo, dt DeploymentTimer, deadline time.Duration, timeout time.Duration) *DeploymentReaper {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DeploymentReaper{
+		repo:     repo,
+		log:      log.Logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		dt:       dt,
+		done:     make(chan bool),
+		deadline: deadline,
+		timeout:  timeout,
+	}
+}
+
+func (dr *DeploymentReaper) Start() {
+	go dr.start()
+	dr.log.Info("deployment reaper started", zap.Duration("deadline", dr.deadline))
+}
+
+func (dr *DeploymentReaper) run(ctx context.Context) error {
+	deployments, err := dr.repo.StuckDeployments(ctx, time.Now().UTC().Add(-dr.deadline))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	for i := range deployments {
+		err = dr.dt.TimeoutDeployment(ctx, &deployments[i], dr.deadline)
+		if err != nil {
+			dr.log.Error("failed to time out deployment", zap.String("id", deployments[i].ID.String()), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func (dr *DeploymentReaper) start() {
+	for {
+		select {
+		case <-dr.ctx.Done():
+			dr.log.Info("deployment reaper stopped")
+			close(dr.done)
+			return
+		default:
+			ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), log.RequestIDKey, log.GetNextRequestID()), dr.timeout)
+			err := dr.run(ctx)
+			cancel()
+			if err != nil {
+				dr.log.Error("an error occurred in the deployment reaper", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-dr.ctx.Done():
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
+func (dr *DeploymentReaper) Stop() {
+	dr.cancel()
+	<-dr.done
+}
+
+// TimeoutDeployment marks a stuck deployment as timed out and releases the namespace it was holding on to
+func (dq *Queue) TimeoutDeployment(ctx context.Context, deployment *data.Deployment, deadline time.Duration) error {
+	reason := fmt.Sprintf("deployment timed out after %s without a reply from the scheduler", deadline)
+	if deployment.State == data.DeploymentStateQueued {
+		reason = fmt.Sprintf("deployment timed out after %s without being scheduled", deadline)
+	}
+
+	updated, err := dq.repo.UpdateDeploymentResult(ctx, deployment.ID, data.DeploymentStateTimedOut)
+	if err != nil {
+		if _, ok := err.(data.NotFoundError); ok {
+			// the scheduler replied or it was cancelled in the meantime
+			return nil
+		}
+		return errors.Wrap(err)
+	}
+
+	var options eve.NamespacePlanOptions
+	err = updated.PlanOptions.Unmarshal(&options)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	dq.Logger(ctx).Warn("deployment timed out", zap.String("id", updated.ID.String()), zap.String("state", string(deployment.State)), zap.Duration("deadline", deadline))
+	statDeploymentTimedOutCount.WithLabelValues(options.EnvironmentName, options.NamespaceRequest.Name).Inc()
+
+	dq.releaseDeployment(ctx, updated, options, data.DeploymentMessageSourceTimeout, reason)
+
+	return nil
+}
@@ -0,0 +1,64 @@
+// +build local
+
+package plans_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unanet/eve/internal/config"
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service/crud"
+	"github.com/unanet/eve/internal/service/plans"
+)
+
+func TestQueue_TimeoutDeployment(t *testing.T) {
+	ctx := context.Background()
+	db, err := data.GetDBWithTimeout(config.GetDBConfig().DbConnectionString(), 10*time.Second)
+	require.NoError(t, err)
+	repo := data.NewRepo(db)
+
+	deployment := createDeployment(t, repo, data.DeploymentStateScheduled)
+	_, err = repo.UpdateDeploymentReceiptHandle(ctx, deployment.ID, "timeout-test")
+	require.NoError(t, err)
+
+	// anything that hasn't changed up until now is stuck as far as the reaper is concerned
+	stuck, err := repo.StuckDeployments(ctx, time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	var found *data.Deployment
+	for i, x := range stuck {
+		if x.ID == deployment.ID {
+			found = &stuck[i]
+		}
+	}
+	require.NotNil(t, found)
+
+	worker := &schedulerWorker{}
+	dq := plans.NewQueue(worker, repo, crud.NewManager(repo, nil), nil, nil, discardNotifications{}, discardProgress{}, nil)
+	require.NoError(t, dq.TimeoutDeployment(ctx, found, time.Hour))
+
+	timedOut, err := repo.DeploymentByID(ctx, deployment.ID)
+	require.NoError(t, err)
+	require.Equal(t, data.DeploymentStateTimedOut, timedOut.State)
+	// the deploy message is removed so the namespace queue group is unblocked
+	require.Len(t, worker.deleted, 1)
+	require.Equal(t, "timeout-test", worker.deleted[0].ReceiptHandle)
+
+	messages, err := repo.DeploymentMessagesByDeploymentID(ctx, deployment.ID)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, data.DeploymentMessageSourceTimeout, messages[0].Source)
+
+	// the scheduler replying or a cancel in the meantime leaves nothing to time out
+	require.NoError(t, dq.TimeoutDeployment(ctx, found, time.Hour))
+	require.Len(t, worker.deleted, 1)
+
+	stuck, err = repo.StuckDeployments(ctx, time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	for _, x := range stuck {
+		require.NotEqual(t, deployment.ID, x.ID)
+	}
+}
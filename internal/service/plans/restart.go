@@ -0,0 +1,50 @@
+package plans
+
+import (
+	"context"
+
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func (d *PlanGenerator) RestartService(ctx context.Context, serviceID int, restart eve.ServiceRestart) ([]*eve.DeploymentPlanOptions, error) {
+	dService, err := d.repo.ServiceByID(ctx, serviceID)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	return d.queueRestarts(ctx, []data.Service{*dService}, restart.User, restart.CallbackURL)
+}
+
+// RestartServices restarts exactly the services named in the request, one restart plan is queued per namespace
+func (d *PlanGenerator) RestartServices(ctx context.Context, restart eve.ServiceRestart) ([]*eve.DeploymentPlanOptions, error) {
+	if len(restart.Services) == 0 {
+		return nil, errors.BadRequest("at least one service is required to restart")
+	}
+
+	var services []data.Service
+	for _, x := range restart.Services {
+		namespaces, err := d.repo.NamespacesByEnvironmentName(ctx, x.Environment)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		included, _ := namespaces.FilterNamespaces(func(namespace data.Namespace) bool {
+			return namespace.Alias == x.NamespaceAlias
+		})
+		if len(included) == 0 {
+			return nil, errors.NotFoundf("namespace: %s, not found in environment: %s", x.NamespaceAlias, x.Environment)
+		}
+
+		dService, err := d.repo.ServiceByName(ctx, x.Service, included[0].Name)
+		if err != nil {
+			return nil, service.CheckForNotFoundError(err)
+		}
+		services = append(services, *dService)
+	}
+
+	return d.queueRestarts(ctx, services, restart.User, restart.CallbackURL)
+}
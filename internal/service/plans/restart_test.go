@@ -0,0 +1,84 @@
+// +build local
+
+package plans_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unanet/eve/internal/config"
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service/plans"
+	"github.com/unanet/eve/pkg/eve"
+	"github.com/unanet/eve/pkg/queue"
+)
+
+// requestedVersion resolves every version to itself and records what was asked for
+type requestedVersion struct {
+	versions []string
+}
+
+func (r *requestedVersion) GetLatestVersion(ctx context.Context, repository string, path string, version string) (string, error) {
+	r.versions = append(r.versions, version)
+	return version, nil
+}
+
+type capturedMessages struct {
+	messages []*queue.M
+}
+
+func (c *capturedMessages) Message(ctx context.Context, m *queue.M) error {
+	c.messages = append(c.messages, m)
+	return nil
+}
+
+type discardEvents struct{}
+
+func (discardEvents) Publish(ctx context.Context, event eve.Event) {}
+
+func TestPlanGenerator_RestartServices(t *testing.T) {
+	ctx := context.Background()
+	db, err := data.GetDBWithTimeout(config.GetDBConfig().DbConnectionString(), 10*time.Second)
+	require.NoError(t, err)
+	repo := data.NewRepo(db)
+
+	services, err := repo.Services(ctx)
+	require.NoError(t, err)
+	var deployed *data.Service
+	for i, x := range services {
+		if x.DeployedVersion.Valid && len(x.DeployedVersion.String) > 0 {
+			deployed = &services[i]
+			break
+		}
+	}
+	if deployed == nil {
+		t.Skip("no deployed service to restart")
+	}
+
+	namespace, err := repo.NamespaceByID(ctx, deployed.NamespaceID)
+	require.NoError(t, err)
+	env, err := repo.EnvironmentByID(ctx, namespace.EnvironmentID)
+	require.NoError(t, err)
+
+	q := &capturedMessages{}
+	vq := &requestedVersion{}
+	restarts, err := plans.NewPlanGenerator(repo, vq, q, discardEvents{}).RestartServices(ctx, eve.ServiceRestart{
+		User: "restart-test",
+		Services: []eve.RestartService{{
+			Service:        deployed.Name,
+			NamespaceAlias: namespace.Alias,
+			Environment:    env.Name,
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, restarts, 1)
+	require.Len(t, restarts[0].Artifacts, 1)
+	require.Equal(t, deployed.Name, restarts[0].Artifacts[0].Name)
+	require.Len(t, restarts[0].DeploymentIDs, 1)
+	require.Len(t, q.messages, 1)
+	// the deployed version is requested as is, never widened to a wildcard
+	require.Equal(t, []string{deployed.DeployedVersion.String}, vq.versions)
+}
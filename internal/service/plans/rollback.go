@@ -0,0 +1,85 @@
+package plans
+
+import (
+	"context"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+// QueueRollback queues a new deployment plan that pins every service/job changed by the supplied deployment
+// back to the version it replaced
+func (d *PlanGenerator) QueueRollback(ctx context.Context, id string, rollback eve.DeploymentRollback) (*eve.DeploymentPlanOptions, error) {
+	deploymentID, err := uuid.FromString(id)
+	if err != nil {
+		return nil, errors.NewRestError(400, "invalid deployment id")
+	}
+
+	deployment, err := d.repo.DeploymentByID(ctx, deploymentID)
+	if err != nil {
+		if _, ok := err.(data.NotFoundError); ok {
+			return nil, errors.NotFoundf("deployment: %s, not found", deploymentID)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	if deployment.State != data.DeploymentStateCompleted && deployment.State != data.DeploymentStatePartial {
+		return nil, errors.BadRequestf("deployment: %s, is %s and cannot be rolled back", deploymentID, deployment.State)
+	}
+
+	var nsOptions eve.NamespacePlanOptions
+	err = deployment.PlanOptions.Unmarshal(&nsOptions)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	if nsOptions.Type == eve.DeploymentPlanTypeRestart {
+		return nil, errors.BadRequestf("deployment: %s, is a restart and has nothing to roll back", deploymentID)
+	}
+
+	artifacts, err := d.repo.DeploymentArtifactsByDeploymentID(ctx, deploymentID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	// ForceDeploy is left off on purpose, an artifact that's already back at its previous version has nothing to roll
+	// back and is reported as already up to date instead of being redeployed
+	options := eve.DeploymentPlanOptions{
+		User:             rollback.User,
+		DryRun:           rollback.DryRun,
+		CallbackURL:      rollback.CallbackURL,
+		Environment:      nsOptions.EnvironmentName,
+		NamespaceAliases: eve.StringList{nsOptions.NamespaceRequest.Alias},
+		Type:             nsOptions.Type,
+		RollbackOf:       &deployment.ID,
+	}
+
+	for _, x := range artifacts {
+		if x.Result != eve.DeployArtifactResultSuccess.String() {
+			continue
+		}
+
+		if !x.FromVersion.Valid || len(x.FromVersion.String) == 0 {
+			options.Message("%s had no previous version to roll back to", x.Name)
+			continue
+		}
+		options.Artifacts = append(options.Artifacts, &eve.ArtifactDefinition{
+			Name:             x.Name,
+			RequestedVersion: x.FromVersion.String,
+		})
+	}
+
+	if len(options.Artifacts) == 0 {
+		return nil, errors.NewRestError(400, "deployment: %s, has no previous versions to roll back to: %v", deploymentID, options.Messages)
+	}
+
+	err = d.QueuePlan(ctx, &options)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	return &options, nil
+}
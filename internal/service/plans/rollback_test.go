@@ -0,0 +1,90 @@
+// +build local
+
+package plans_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/unanet/go/pkg/json"
+
+	"github.com/unanet/eve/internal/config"
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service/plans"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func TestPlanGenerator_QueueRollback(t *testing.T) {
+	ctx := context.Background()
+	db, err := data.GetDBWithTimeout(config.GetDBConfig().DbConnectionString(), 10*time.Second)
+	require.NoError(t, err)
+	repo := data.NewRepo(db)
+
+	services, err := repo.Services(ctx)
+	require.NoError(t, err)
+	var deployed *data.Service
+	for i, x := range services {
+		if x.DeployedVersion.Valid && len(x.DeployedVersion.String) > 0 {
+			deployed = &services[i]
+			break
+		}
+	}
+	if deployed == nil {
+		t.Skip("no deployed service to roll back")
+	}
+
+	namespace, err := repo.NamespaceByID(ctx, deployed.NamespaceID)
+	require.NoError(t, err)
+	env, err := repo.EnvironmentByID(ctx, namespace.EnvironmentID)
+	require.NoError(t, err)
+
+	options, err := json.StructToJsonObject(eve.NamespacePlanOptions{
+		NamespaceRequest: &eve.NamespaceRequest{ID: namespace.ID, Alias: namespace.Alias, Name: namespace.Name, ClusterID: namespace.ClusterID},
+		EnvironmentID:    env.ID,
+		EnvironmentName:  env.Name,
+		Type:             eve.DeploymentPlanTypeApplication,
+	})
+	require.NoError(t, err)
+	deployment := &data.Deployment{
+		EnvironmentID: env.ID,
+		NamespaceID:   namespace.ID,
+		PlanOptions:   options,
+		State:         data.DeploymentStateCompleted,
+		User:          "rollback-test",
+	}
+	require.NoError(t, repo.CreateDeployment(ctx, deployment))
+
+	// the deployment being rolled back moved the service from its deployed version, it's recorded as not deployed so
+	// the service itself is left alone
+	previous := deployed.DeployedVersion.String
+	require.NoError(t, repo.RecordDeployResult(ctx, &data.DeploymentArtifact{
+		DeploymentID: deployment.ID,
+		ServiceID:    sql.NullInt32{Int32: int32(deployed.ID), Valid: true},
+		FromVersion:  sql.NullString{String: previous, Valid: true},
+		ToVersion:    previous + "-rollback-test",
+		Result:       eve.DeployArtifactResultSuccess.String(),
+	}, false))
+
+	q := &capturedMessages{}
+	vq := &requestedVersion{}
+	rollback, err := plans.NewPlanGenerator(repo, vq, q, discardEvents{}).QueueRollback(ctx, deployment.ID.String(), eve.DeploymentRollback{
+		User: "rollback-test",
+	})
+	require.NoError(t, err)
+	require.Equal(t, deployment.ID, *rollback.RollbackOf)
+	// a service that's already back at its previous version is skipped rather than redeployed
+	require.False(t, rollback.ForceDeploy)
+	require.Len(t, rollback.Artifacts, 1)
+	require.Equal(t, previous, rollback.Artifacts[0].AvailableVersion)
+	// the recorded version is requested as is, never widened to a wildcard
+	require.Equal(t, []string{previous}, vq.versions)
+	require.Len(t, rollback.DeploymentIDs, 1)
+	require.Len(t, q.messages, 1)
+
+	queued, err := repo.DeploymentByID(ctx, rollback.DeploymentIDs[0])
+	require.NoError(t, err)
+	require.Equal(t, deployment.ID, queued.RollbackOf.UUID)
+}
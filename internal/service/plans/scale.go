@@ -0,0 +1,176 @@
+package plans
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/internal/data"
+	"github.com/unanet/eve/internal/service"
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func (d *PlanGenerator) ScaleService(ctx context.Context, serviceID int, scale eve.ServiceScale) (*eve.ServiceScaleResult, error) {
+	dService, err := d.repo.ServiceByID(ctx, serviceID)
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	return d.scaleServices(ctx, []data.Service{*dService}, scale)
+}
+
+// ScaleNamespace scales every service in the namespace (id or name), or just the ones named in the request
+func (d *PlanGenerator) ScaleNamespace(ctx context.Context, namespace string, scale eve.ServiceScale) (*eve.ServiceScaleResult, error) {
+	var dNamespace *data.Namespace
+	var err error
+	if intID, convErr := strconv.Atoi(namespace); convErr == nil {
+		dNamespace, err = d.repo.NamespaceByID(ctx, intID)
+	} else {
+		dNamespace, err = d.repo.NamespaceByName(ctx, namespace)
+	}
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	services, err := d.repo.ServicesByNamespaceID(ctx, dNamespace.ID)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	return d.scaleServices(ctx, filterServices(services, scale.Services), scale)
+}
+
+// ScaleEnvironment scales every service in every namespace of the environment (id or name), or just the ones named in the request
+func (d *PlanGenerator) ScaleEnvironment(ctx context.Context, environment string, scale eve.ServiceScale) (*eve.ServiceScaleResult, error) {
+	var env *data.Environment
+	var err error
+	if intID, convErr := strconv.Atoi(environment); convErr == nil {
+		env, err = d.repo.EnvironmentByID(ctx, intID)
+	} else {
+		env, err = d.repo.EnvironmentByName(ctx, environment)
+	}
+	if err != nil {
+		return nil, service.CheckForNotFoundError(err)
+	}
+
+	services, err := d.repo.Services(ctx, data.Where("n.environment_id", env.ID))
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	return d.scaleServices(ctx, filterServices(services, scale.Services), scale)
+}
+
+func filterServices(services []data.Service, names eve.StringList) []data.Service {
+	if len(names) == 0 {
+		return services
+	}
+
+	var filtered []data.Service
+	for _, x := range services {
+		if names.Contains(x.Name) {
+			filtered = append(filtered, x)
+		}
+	}
+	return filtered
+}
+
+func (d *PlanGenerator) scaleServices(ctx context.Context, services []data.Service, scale eve.ServiceScale) (*eve.ServiceScaleResult, error) {
+	if len(services) == 0 {
+		return nil, errors.NotFoundf("no services found to scale")
+	}
+
+	serviceIDs := make([]int, 0, len(services))
+	for _, x := range services {
+		serviceIDs = append(serviceIDs, x.ID)
+	}
+	fromCounts, err := d.repo.UpdateServiceCounts(ctx, serviceIDs, *scale.Count, scale.User)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	result := eve.ServiceScaleResult{}
+	for i, x := range services {
+		services[i].Count = *scale.Count
+
+		result.Services = append(result.Services, eve.ScaledService{
+			ServiceID:     x.ID,
+			Name:          x.Name,
+			NamespaceName: x.NamespaceName,
+			FromCount:     fromCounts[x.ID],
+			Count:         *scale.Count,
+		})
+	}
+
+	if !scale.Restart {
+		return &result, nil
+	}
+
+	restarts, err := d.queueRestarts(ctx, services, scale.User, scale.CallbackURL)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	result.Restarts = restarts
+
+	return &result, nil
+}
+
+// queueRestarts queues a restart plan per namespace for the supplied services. Each service is pinned to the version
+// that's already deployed so a restart never picks up a newer version from artifactory
+func (d *PlanGenerator) queueRestarts(ctx context.Context, services []data.Service, user string, callbackURL string) ([]*eve.DeploymentPlanOptions, error) {
+	var namespaceIDs []int
+	byNamespace := make(map[int][]data.Service)
+	for _, x := range services {
+		if _, ok := byNamespace[x.NamespaceID]; !ok {
+			namespaceIDs = append(namespaceIDs, x.NamespaceID)
+		}
+		byNamespace[x.NamespaceID] = append(byNamespace[x.NamespaceID], x)
+	}
+
+	var restarts []*eve.DeploymentPlanOptions
+	for _, namespaceID := range namespaceIDs {
+		namespace, err := d.repo.NamespaceByID(ctx, namespaceID)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		env, err := d.repo.EnvironmentByID(ctx, namespace.EnvironmentID)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		options := &eve.DeploymentPlanOptions{
+			Environment:      env.Name,
+			NamespaceAliases: eve.StringList{namespace.Alias},
+			Type:             eve.DeploymentPlanTypeRestart,
+			ForceDeploy:      true,
+			User:             user,
+			CallbackURL:      callbackURL,
+		}
+
+		for _, x := range byNamespace[namespaceID] {
+			if !x.DeployedVersion.Valid || len(x.DeployedVersion.String) == 0 {
+				options.Message("service: %s, has never been deployed and was not restarted", x.Name)
+				continue
+			}
+			options.Artifacts = append(options.Artifacts, &eve.ArtifactDefinition{
+				Name:             x.Name,
+				RequestedVersion: x.DeployedVersion.String,
+			})
+		}
+
+		if len(options.Artifacts) == 0 {
+			restarts = append(restarts, options)
+			continue
+		}
+
+		err = d.QueuePlan(ctx, options)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		restarts = append(restarts, options)
+	}
+
+	return restarts, nil
+}
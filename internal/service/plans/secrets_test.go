@@ -0,0 +1,77 @@
+package plans
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/unanet/eve/pkg/eve"
+	"github.com/unanet/eve/pkg/storage"
+)
+
+type resolvedSecrets struct{}
+
+func (resolvedSecrets) Resolve(ctx context.Context, metadata eve.MetadataField) (eve.MetadataField, []string, error) {
+	return eve.MetadataField{"password": "hunter2"}, []string{"/password"}, nil
+}
+
+type capturedUpload struct {
+	body []byte
+}
+
+func (c *capturedUpload) Upload(ctx context.Context, key string, body []byte) (*storage.Location, error) {
+	c.body = body
+	return &storage.Location{Key: key}, nil
+}
+
+func (c *capturedUpload) Download(ctx context.Context, location *storage.Location) ([]byte, error) {
+	return c.body, nil
+}
+
+func TestQueue_SecretsOnlyTravelInTheSchedulerMessage(t *testing.T) {
+	ctx := context.Background()
+	dq := &Queue{secrets: resolvedSecrets{}}
+
+	artifact := &eve.DeployArtifact{ArtifactName: "api"}
+	err := dq.setMetadata(ctx, artifact, eve.MetadataField{
+		"password": map[string]interface{}{eve.SecretReferenceKey: "vault://secret/api#password"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &eve.NSDeploymentPlan{
+		Services: eve.DeployServices{{DeployArtifact: artifact, ServiceID: 7, ServiceName: "api"}},
+	}
+	uploads := &capturedUpload{}
+	location, err := eve.MarshalNSDeploymentPlanToS3LocationBody(ctx, uploads, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(uploads.body, []byte("hunter2")) {
+		t.Errorf("the uploaded plan contains a resolved secret: %s", uploads.body)
+	}
+	if bytes.Contains(location, []byte("hunter2")) {
+		t.Errorf("the stored location contains a resolved secret: %s", location)
+	}
+
+	message, err := eve.PlanMessageBody(location, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received, err := eve.UnMarshalNSDeploymentFromS3LocationBody(ctx, uploads, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := received.Services[0].Metadata["password"]; got != "hunter2" {
+		t.Errorf("the scheduler got password = %v, want the resolved secret", got)
+	}
+
+	stored, err := eve.UnMarshalNSDeploymentFromS3LocationBody(ctx, uploads, location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stored.Services[0].Metadata["password"]; got != eve.SecretMask {
+		t.Errorf("the stored plan has password = %v, want it masked", got)
+	}
+}
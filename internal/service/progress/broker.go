@@ -0,0 +1,200 @@
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/log"
+	"go.uber.org/zap"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+const (
+	channel = "eve_deployment_progress"
+	// postgres notifications are limited to 8000 bytes
+	maxPayload       = 7900
+	subscriberBuffer = 64
+	// replayBuffer is how many of the latest events are kept for clients that reconnect with a Last-Event-ID
+	replayBuffer = 512
+)
+
+type Notifier interface {
+	Notify(ctx context.Context, channel string, payload string) error
+}
+
+// Subscription receives the events that match its filter until it's closed. Events are dropped rather than blocking
+// the broker when a subscriber can't keep up
+type Subscription struct {
+	C      chan eve.DeploymentProgress
+	filter func(eve.DeploymentProgress) bool
+	b      *Broker
+}
+
+func (s *Subscription) Close() {
+	s.b.mutex.Lock()
+	defer s.b.mutex.Unlock()
+	delete(s.b.subscribers, s)
+}
+
+// Broker sends deployment progress through postgres LISTEN/NOTIFY, so a client streaming from any api instance sees
+// events from whichever instance handled the queue message
+type Broker struct {
+	log         *zap.Logger
+	notifier    Notifier
+	dsn         string
+	listener    *pq.Listener
+	mutex       sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	recent      []eve.DeploymentProgress
+	done        chan bool
+}
+
+func NewBroker(notifier Notifier, dsn string) *Broker {
+	return &Broker{
+		log:         log.Logger,
+		notifier:    notifier,
+		dsn:         dsn,
+		subscribers: make(map[*Subscription]struct{}),
+		done:        make(chan bool),
+	}
+}
+
+func (b *Broker) Start() error {
+	b.listener = pq.NewListener(b.dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			b.log.Warn("deployment progress listener error", zap.Error(err))
+		}
+	})
+
+	err := b.listener.Listen(channel)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	go b.listen()
+	b.log.Info("deployment progress broker started")
+	return nil
+}
+
+func (b *Broker) listen() {
+	for n := range b.listener.Notify {
+		// a nil notification means the connection was re-established, anything sent in between is gone
+		if n == nil {
+			continue
+		}
+
+		var p eve.DeploymentProgress
+		if err := json.Unmarshal([]byte(n.Extra), &p); err != nil {
+			b.log.Warn("invalid deployment progress notification", zap.Error(err))
+			continue
+		}
+		b.dispatch(p)
+	}
+	close(b.done)
+}
+
+func (b *Broker) dispatch(p eve.DeploymentProgress) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.recent = append(b.recent, p)
+	if len(b.recent) > replayBuffer {
+		b.recent = b.recent[len(b.recent)-replayBuffer:]
+	}
+	for s := range b.subscribers {
+		if !s.filter(p) {
+			continue
+		}
+		select {
+		case s.C <- p:
+		default:
+			b.log.Debug("deployment progress subscriber is behind, dropping event", zap.String("id", p.DeploymentID.String()))
+		}
+	}
+}
+
+func (b *Broker) Stop() {
+	if b.listener == nil {
+		return
+	}
+	if err := b.listener.Close(); err != nil {
+		b.log.Warn("failed to close the deployment progress listener", zap.Error(err))
+	}
+	<-b.done
+	b.log.Info("deployment progress broker stopped")
+}
+
+func (b *Broker) Subscribe(filter func(eve.DeploymentProgress) bool) *Subscription {
+	s, _ := b.SubscribeSince(filter, 0)
+	return s
+}
+
+// SubscribeSince also returns the buffered events that match the filter and came after the event with lastID, so a
+// client that reconnects doesn't miss what was published in between. A lastID of 0 doesn't replay anything
+func (b *Broker) SubscribeSince(filter func(eve.DeploymentProgress) bool, lastID int64) (*Subscription, []eve.DeploymentProgress) {
+	s := &Subscription{
+		C:      make(chan eve.DeploymentProgress, subscriberBuffer),
+		filter: filter,
+		b:      b,
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.subscribers[s] = struct{}{}
+
+	var missed []eve.DeploymentProgress
+	if lastID != 0 {
+		for _, x := range b.recent {
+			if EventID(x) > lastID && filter(x) {
+				missed = append(missed, x)
+			}
+		}
+	}
+	return s, missed
+}
+
+// EventID identifies an event the same way on every api instance, it's when the event was published
+func EventID(p eve.DeploymentProgress) int64 {
+	return p.CreatedAt.UnixNano()
+}
+
+// Publish sends the event to every subscriber on every api instance, failures are only logged
+func (b *Broker) Publish(ctx context.Context, p eve.DeploymentProgress) {
+	p.CreatedAt = time.Now().UTC()
+	payload, err := marshal(p)
+	if err != nil {
+		b.log.Warn("failed to marshal the deployment progress", zap.Error(err))
+		return
+	}
+
+	err = b.notifier.Notify(ctx, channel, payload)
+	if err != nil {
+		b.log.Warn("failed to publish the deployment progress", zap.String("req_id", log.GetReqID(ctx)), zap.Error(err))
+	}
+}
+
+// marshal drops the artifacts and then the messages when the event won't fit in a notification
+func marshal(p eve.DeploymentProgress) (string, error) {
+	for {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return "", errors.Wrap(err)
+		}
+		if len(b) <= maxPayload {
+			return string(b), nil
+		}
+
+		p.Truncated = true
+		switch {
+		case len(p.Artifacts) > 0:
+			p.Artifacts = nil
+		case len(p.Messages) > 0:
+			p.Messages = nil
+		default:
+			return "", errors.Wrapf("deployment progress is too large: %d bytes", len(b))
+		}
+	}
+}
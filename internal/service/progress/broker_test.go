@@ -0,0 +1,73 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func TestMarshal_Truncates(t *testing.T) {
+	p := eve.DeploymentProgress{
+		Type:     eve.DeploymentProgressComplete,
+		Messages: []string{strings.Repeat("x", maxPayload)},
+	}
+
+	payload, err := marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payload) > maxPayload || !strings.Contains(payload, `"truncated":true`) || strings.Contains(payload, "xxx") {
+		t.Errorf("expected the messages to be dropped, got: %s", payload)
+	}
+
+	p.Messages = []string{"done"}
+	payload, err = marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(payload, "truncated") || !strings.Contains(payload, "done") {
+		t.Errorf("expected the event to be left alone, got: %s", payload)
+	}
+}
+
+func TestBroker_Dispatch(t *testing.T) {
+	b := NewBroker(nil, "")
+	prod := b.Subscribe(func(p eve.DeploymentProgress) bool { return p.Environment == "prod" })
+	qa := b.Subscribe(func(p eve.DeploymentProgress) bool { return p.Environment == "qa" })
+	defer qa.Close()
+
+	b.dispatch(eve.DeploymentProgress{Environment: "prod"})
+	if len(prod.C) != 1 || len(qa.C) != 0 {
+		t.Errorf("expected only the prod subscriber to get the event, prod: %d, qa: %d", len(prod.C), len(qa.C))
+	}
+
+	prod.Close()
+	b.dispatch(eve.DeploymentProgress{Environment: "prod"})
+	if len(prod.C) != 1 {
+		t.Errorf("expected a closed subscription to stop receiving events")
+	}
+}
+
+func TestBroker_SubscribeSince(t *testing.T) {
+	b := NewBroker(nil, "")
+	published := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		b.dispatch(eve.DeploymentProgress{Environment: "prod", CreatedAt: published.Add(time.Duration(i) * time.Second)})
+	}
+	b.dispatch(eve.DeploymentProgress{Environment: "qa", CreatedAt: published.Add(3 * time.Second)})
+
+	prod := func(p eve.DeploymentProgress) bool { return p.Environment == "prod" }
+	s, missed := b.SubscribeSince(prod, EventID(eve.DeploymentProgress{CreatedAt: published}))
+	defer s.Close()
+	if len(missed) != 2 || EventID(missed[0]) != EventID(eve.DeploymentProgress{CreatedAt: published.Add(time.Second)}) {
+		t.Errorf("expected the 2 prod events after the last id to be replayed, got: %v", missed)
+	}
+
+	s2, missed := b.SubscribeSince(prod, 0)
+	defer s2.Close()
+	if len(missed) != 0 {
+		t.Errorf("expected nothing to be replayed without a last id, got: %v", missed)
+	}
+}
@@ -20,17 +20,24 @@ import (
 	"github.com/unanet/eve/pkg/scm/types"
 )
 
+// EventPublisher sends an event to the subscriptions that match it
+type EventPublisher interface {
+	Publish(ctx context.Context, event eve.Event)
+}
+
 type ReleaseSvc struct {
 	repo              *data.Repo
 	artifactoryClient *artifactory.Client
 	scm               scm.SourceController
+	events            EventPublisher
 }
 
-func NewReleaseSvc(r *data.Repo, a *artifactory.Client, g scm.SourceController) *ReleaseSvc {
+func NewReleaseSvc(r *data.Repo, a *artifactory.Client, g scm.SourceController, events EventPublisher) *ReleaseSvc {
 	return &ReleaseSvc{
 		repo:              r,
 		artifactoryClient: a,
 		scm:               g,
+		events:            events,
 	}
 }
 
@@ -225,6 +232,11 @@ func (svc *ReleaseSvc) Release(ctx context.Context, release eve.Release) (eve.Re
 	success.Message = resp.ToString()
 
 	log.Logger.Info("artifact released", zap.Any("result", success))
+	svc.events.Publish(ctx, eve.Event{
+		Type:      eve.EventReleasePromoted,
+		Artifacts: eve.StringList{success.Artifact},
+		Data:      success,
+	})
 	return success, nil
 }
 
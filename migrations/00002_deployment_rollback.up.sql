@@ -0,0 +1,35 @@
+alter table deployment
+    add column if not exists rollback_of uuid;
+
+alter table deployment drop constraint if exists deployment_rollback_of_fk;
+alter table deployment
+    add constraint deployment_rollback_of_fk
+        foreign key (rollback_of) references deployment
+            on delete set null;
+
+create table if not exists deployment_artifact
+(
+    id            serial                  not null,
+    deployment_id uuid                    not null,
+    service_id    integer,
+    job_id        integer,
+    from_version  varchar(50),
+    to_version    varchar(50)             not null,
+    created_at    timestamp default now() not null,
+    constraint deployment_artifact_pk
+        primary key (id),
+    constraint deployment_artifact_deployment_id_fk
+        foreign key (deployment_id) references deployment
+            on delete cascade,
+    constraint deployment_artifact_service_id_fk
+        foreign key (service_id) references service
+            on delete cascade,
+    constraint deployment_artifact_job_id_fk
+        foreign key (job_id) references job
+            on delete cascade,
+    constraint chk_service_or_job_must_be_set
+        check (num_nonnulls(service_id, job_id) = 1)
+);
+
+create index if not exists deployment_artifact_deployment_id_index
+    on deployment_artifact (deployment_id);
@@ -0,0 +1,21 @@
+alter table deployment_artifact add column if not exists result varchar(25);
+alter table deployment_artifact add column if not exists exit_code integer default 0 not null;
+
+-- only successful deployments were recorded before this migration
+update deployment_artifact set result = 'success' where result is null;
+alter table deployment_artifact alter column result set not null;
+
+create index if not exists deployment_artifact_service_id_created_at_index
+    on deployment_artifact (service_id, created_at);
+
+create index if not exists deployment_artifact_job_id_created_at_index
+    on deployment_artifact (job_id, created_at);
+
+-- a redelivered result is recorded once per service/job of a deployment
+create unique index if not exists deployment_artifact_deployment_id_service_id_uindex
+    on deployment_artifact (deployment_id, service_id)
+    where service_id is not null;
+
+create unique index if not exists deployment_artifact_deployment_id_job_id_uindex
+    on deployment_artifact (deployment_id, job_id)
+    where job_id is not null;
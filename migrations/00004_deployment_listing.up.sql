@@ -0,0 +1,5 @@
+create index if not exists deployment_created_at_id_index
+    on deployment (created_at, id);
+
+create index if not exists deployment_environment_id_namespace_id_index
+    on deployment (environment_id, namespace_id);
@@ -0,0 +1,23 @@
+alter table deployment_artifact add column if not exists requested_version varchar(50);
+alter table deployment_artifact add column if not exists deployed_version varchar(50);
+
+create table if not exists deployment_message
+(
+    id            serial                  not null,
+    deployment_id uuid                    not null,
+    source        varchar(25)             not null,
+    message       text                    not null,
+    created_at    timestamp default now() not null,
+    constraint deployment_message_pk
+        primary key (id),
+    constraint deployment_message_deployment_id_fk
+        foreign key (deployment_id) references deployment
+            on delete cascade
+);
+
+create index if not exists deployment_message_deployment_id_index
+    on deployment_message (deployment_id);
+
+-- a redelivered scheduler update records its messages again, md5 keeps long messages under the btree size limit
+create unique index if not exists deployment_message_deployment_id_source_message_uindex
+    on deployment_message (deployment_id, source, md5(message));
@@ -0,0 +1,4 @@
+alter type deployment_state add value if not exists 'failed';
+alter type deployment_state add value if not exists 'partial';
+alter type deployment_state add value if not exists 'cancelled';
+alter type deployment_state add value if not exists 'timed_out';
@@ -0,0 +1,17 @@
+create table if not exists service_count_history
+(
+    id         serial                  not null,
+    service_id integer                 not null,
+    from_count integer                 not null,
+    to_count   integer                 not null,
+    "user"     varchar(50)             not null,
+    created_at timestamp default now() not null,
+    constraint service_count_history_pk
+        primary key (id),
+    constraint service_count_history_service_id_fk
+        foreign key (service_id) references service
+            on delete cascade
+);
+
+create index if not exists service_count_history_service_id_index
+    on service_count_history (service_id);
@@ -0,0 +1,38 @@
+create table if not exists queue_message
+(
+    id             bigserial                                   not null,
+    queue          varchar(500)                                not null,
+    message_id     uuid      default uuid_generate_v4()        not null,
+    group_id       varchar(128)                                not null,
+    dedupe_id      varchar(128),
+    command        varchar(100)                                not null,
+    req_id         varchar(100),
+    eve_id         uuid,
+    body           text                                        not null,
+    receipt_handle uuid,
+    receive_count  integer   default 0                         not null,
+    visible_at     timestamp default now()                     not null,
+    deleted_at     timestamp,
+    created_at     timestamp default now()                     not null,
+    constraint queue_message_pk
+        primary key (id)
+);
+
+create unique index if not exists queue_message_message_id_uindex
+    on queue_message (message_id);
+
+create index if not exists queue_message_queue_group_id_index
+    on queue_message (queue, group_id, id)
+    where deleted_at is null;
+
+create index if not exists queue_message_queue_dedupe_id_index
+    on queue_message (queue, dedupe_id)
+    where dedupe_id is not null;
+
+create index if not exists queue_message_receipt_handle_index
+    on queue_message (receipt_handle);
+
+-- a message that hasn't been deleted can't be sent twice, even by two concurrent sends
+create unique index if not exists queue_message_queue_dedupe_id_uindex
+    on queue_message (queue, dedupe_id)
+    where dedupe_id is not null and deleted_at is null;
@@ -0,0 +1,9 @@
+create table if not exists plan_storage
+(
+    key        varchar(500)            not null,
+    body       bytea                   not null,
+    created_at timestamp default now() not null,
+    updated_at timestamp default now() not null,
+    constraint plan_storage_pk
+        primary key (key)
+);
@@ -0,0 +1,19 @@
+create table if not exists dead_letter_message
+(
+    id            serial                  not null,
+    queue         varchar(500)            not null,
+    message_id    varchar(100)            not null,
+    group_id      varchar(128)            not null,
+    command       varchar(100)            not null,
+    eve_id        uuid,
+    body          text                    not null,
+    error         text                    not null,
+    receive_count integer                 not null,
+    replayed_at   timestamp,
+    created_at    timestamp default now() not null,
+    constraint dead_letter_message_pk
+        primary key (id)
+);
+
+create index if not exists dead_letter_message_created_at_index
+    on dead_letter_message (created_at);
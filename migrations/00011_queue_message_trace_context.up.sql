@@ -0,0 +1 @@
+alter table queue_message add column if not exists trace_context jsonb;
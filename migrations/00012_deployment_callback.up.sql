@@ -0,0 +1,45 @@
+create table if not exists deployment_callback
+(
+    id               serial                  not null,
+    deployment_id    uuid                    not null,
+    url              varchar(2000)           not null,
+    body             jsonb                   not null,
+    state            varchar(25)             not null,
+    attempts         integer   default 0     not null,
+    next_attempt_at  timestamp default now() not null,
+    last_status_code integer,
+    last_error       text,
+    delivered_at     timestamp,
+    created_at       timestamp default now() not null,
+    updated_at       timestamp default now() not null,
+    constraint deployment_callback_pk
+        primary key (id),
+    constraint deployment_callback_deployment_id_fk
+        foreign key (deployment_id) references deployment
+            on delete cascade
+);
+
+create index if not exists deployment_callback_deployment_id_index
+    on deployment_callback (deployment_id);
+
+create index if not exists deployment_callback_next_attempt_at_index
+    on deployment_callback (next_attempt_at)
+    where state = 'pending';
+
+create table if not exists deployment_callback_attempt
+(
+    id                     serial                  not null,
+    deployment_callback_id integer                 not null,
+    status_code            integer,
+    error                  text,
+    elapsed_ms             integer                 not null,
+    created_at             timestamp default now() not null,
+    constraint deployment_callback_attempt_pk
+        primary key (id),
+    constraint deployment_callback_attempt_deployment_callback_id_fk
+        foreign key (deployment_callback_id) references deployment_callback
+            on delete cascade
+);
+
+create index if not exists deployment_callback_attempt_deployment_callback_id_index
+    on deployment_callback_attempt (deployment_callback_id);
@@ -0,0 +1,32 @@
+create table if not exists subscription
+(
+    id          serial                  not null,
+    name        varchar(100)            not null,
+    url         varchar(2000)           not null,
+    secret      varchar(200),
+    events      jsonb                   not null,
+    environment varchar(50),
+    namespace   varchar(50),
+    artifact    varchar(100),
+    disabled    boolean   default false not null,
+    created_at  timestamp default now() not null,
+    updated_at  timestamp default now() not null,
+    constraint subscription_pk
+        primary key (id)
+);
+
+create unique index if not exists subscription_name_uindex
+    on subscription (name);
+
+-- subscription events go through the same outbox as the per request callbacks, some aren't for a deployment
+alter table deployment_callback alter column deployment_id drop not null;
+alter table deployment_callback add column if not exists subscription_id integer;
+alter table deployment_callback add column if not exists event varchar(100);
+alter table deployment_callback drop constraint if exists deployment_callback_subscription_id_fk;
+alter table deployment_callback
+    add constraint deployment_callback_subscription_id_fk
+        foreign key (subscription_id) references subscription
+            on delete cascade;
+
+create index if not exists deployment_callback_subscription_id_index
+    on deployment_callback (subscription_id);
@@ -0,0 +1 @@
+alter table metadata add column if not exists encrypted_keys jsonb default '[]'::jsonb not null;
@@ -0,0 +1,160 @@
+-- history rows get a key so a single revision can be addressed and restored
+alter table metadata_history add column if not exists id serial not null;
+alter table metadata_history drop constraint if exists metadata_history_pk;
+alter table metadata_history
+    add constraint metadata_history_pk
+        primary key (id);
+alter table metadata_history alter column created_by type varchar(200);
+alter table metadata_history alter column deleted_by type varchar(200);
+
+create index if not exists metadata_history_metadata_id_index
+    on metadata_history (metadata_id);
+
+-- the api sets eve.user on the transaction that writes the change, anything else is attributed to the database user
+create or replace function history_user() returns varchar
+    language sql
+as
+$$
+SELECT coalesce(nullif(current_setting('eve.user', true), ''), current_user)::varchar;
+$$;
+
+create or replace function metadata_insert() returns trigger
+    language plpgsql
+as
+$$
+BEGIN
+    INSERT INTO metadata_history
+    (metadata_id, description, value, created, created_by)
+    VALUES (NEW.id, NEW.description, NEW.value, current_timestamp, history_user());
+    RETURN NEW;
+END;
+$$;
+
+create or replace function metadata_delete() returns trigger
+    language plpgsql
+as
+$$
+BEGIN
+    UPDATE metadata_history
+    SET deleted    = current_timestamp,
+        deleted_by = history_user()
+    WHERE deleted IS NULL
+      and metadata_id = OLD.id;
+    RETURN NULL;
+END;
+$$;
+
+-- a key rotation sets eve.rotating, it rewrites the history in place and isn't a new revision
+create or replace function metadata_update() returns trigger
+    language plpgsql
+as
+$$
+BEGIN
+    IF current_setting('eve.rotating', true) = 'true' THEN
+        RETURN NEW;
+    END IF;
+
+    UPDATE metadata_history
+    SET deleted    = current_timestamp,
+        deleted_by = history_user()
+    WHERE deleted IS NULL
+      and metadata_id = OLD.id;
+
+    INSERT INTO metadata_history
+    (metadata_id, description, value, created, created_by)
+    VALUES (NEW.id, NEW.description, NEW.value, current_timestamp, history_user());
+    RETURN NEW;
+
+END;
+$$;
+
+create table if not exists definition_history
+(
+    id                 serial       not null,
+    definition_id      integer      not null,
+    description        varchar(200) not null,
+    definition_type_id integer,
+    data               jsonb        not null,
+    created            timestamp,
+    created_by         varchar(200),
+    deleted            timestamp,
+    deleted_by         varchar(200),
+    constraint definition_history_pk
+        primary key (id)
+);
+
+create index if not exists definition_history_definition_id_index
+    on definition_history (definition_id);
+
+create or replace function definition_insert() returns trigger
+    language plpgsql
+as
+$$
+BEGIN
+    INSERT INTO definition_history
+    (definition_id, description, definition_type_id, data, created, created_by)
+    VALUES (NEW.id, NEW.description, NEW.definition_type_id, NEW.data, current_timestamp, history_user());
+    RETURN NEW;
+END;
+$$;
+
+drop trigger if exists definition_insert_trigger on definition;
+create trigger definition_insert_trigger
+    after insert
+    on definition
+    for each row
+execute procedure definition_insert();
+
+create or replace function definition_delete() returns trigger
+    language plpgsql
+as
+$$
+BEGIN
+    UPDATE definition_history
+    SET deleted    = current_timestamp,
+        deleted_by = history_user()
+    WHERE deleted IS NULL
+      and definition_id = OLD.id;
+    RETURN NULL;
+END;
+$$;
+
+drop trigger if exists definition_delete_trigger on definition;
+create trigger definition_delete_trigger
+    after delete
+    on definition
+    for each row
+execute procedure definition_delete();
+
+create or replace function definition_update() returns trigger
+    language plpgsql
+as
+$$
+BEGIN
+
+    UPDATE definition_history
+    SET deleted    = current_timestamp,
+        deleted_by = history_user()
+    WHERE deleted IS NULL
+      and definition_id = OLD.id;
+
+    INSERT INTO definition_history
+    (definition_id, description, definition_type_id, data, created, created_by)
+    VALUES (NEW.id, NEW.description, NEW.definition_type_id, NEW.data, current_timestamp, history_user());
+    RETURN NEW;
+
+END;
+$$;
+
+drop trigger if exists definition_update_trigger on definition;
+create trigger definition_update_trigger
+    after update
+    on definition
+    for each row
+execute procedure definition_update();
+
+-- the current definitions are the first revision so the next change has something to diff against
+insert into definition_history (definition_id, description, definition_type_id, data, created, created_by)
+select id, description, definition_type_id, data, updated_at, current_user
+from definition d
+where not exists(select 1 from definition_history h where h.definition_id = d.id);
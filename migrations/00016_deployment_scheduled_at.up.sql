@@ -0,0 +1,2 @@
+-- when the plan was sent to the scheduler, updated_at moves on after that so it can't be used to time the run
+alter table deployment add column if not exists scheduled_at timestamp;
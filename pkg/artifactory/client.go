@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/dghubble/sling"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 
 	"github.com/unanet/go/pkg/errors"
@@ -35,7 +36,7 @@ func NewClient(config Config) *Client {
 
 	var httpClient = &http.Client{
 		Timeout:   config.ArtifactoryTimeout,
-		Transport: ehttp.LoggingTransport,
+		Transport: otelhttp.NewTransport(metricsTransport{next: ehttp.LoggingTransport}),
 	}
 
 	if !strings.HasSuffix(config.ArtifactoryBaseUrl, "/") {
@@ -0,0 +1,45 @@
+package artifactory
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	statRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "eve_artifactory_request_duration_seconds",
+			Help: "Time it takes for Artifactory to respond",
+		}, []string{"method", "code"})
+
+	statRequestErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eve_artifactory_request_errors_total",
+			Help: "The total number of Artifactory requests that failed or returned a 5xx",
+		}, []string{"method"})
+)
+
+// metricsTransport records the latency and errors of every request made to Artifactory
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t metricsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	now := time.Now()
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		statRequestDuration.WithLabelValues(r.Method, "error").Observe(time.Since(now).Seconds())
+		statRequestErrors.WithLabelValues(r.Method).Inc()
+		return resp, err
+	}
+
+	statRequestDuration.WithLabelValues(r.Method, strconv.Itoa(resp.StatusCode)).Observe(time.Since(now).Seconds())
+	if resp.StatusCode >= http.StatusInternalServerError {
+		statRequestErrors.WithLabelValues(r.Method).Inc()
+	}
+	return resp, nil
+}
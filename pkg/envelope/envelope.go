@@ -0,0 +1,197 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Key marks an encrypted value, e.g. {"$encrypted": {"key_id": "2021-08", "data_key": "...", "value": "..."}}
+const Key = "$encrypted"
+
+type Config struct {
+	MetadataEncryptionKeys  map[string]string `envconfig:"METADATA_ENCRYPTION_KEYS"`
+	MetadataEncryptionKeyID string            `envconfig:"METADATA_ENCRYPTION_KEY_ID"`
+}
+
+// Keyring envelope encrypts values. Every value gets its own data key which is encrypted (wrapped) with the current
+// master key, older master keys are kept so their values can still be decrypted until they've been rotated
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// New decodes the base64 encoded 256 bit master keys, with none configured encryption is disabled
+func New(cfg Config) (*Keyring, error) {
+	k := &Keyring{
+		current: cfg.MetadataEncryptionKeyID,
+		keys:    make(map[string][]byte),
+	}
+
+	for id, encoded := range cfg.MetadataEncryptionKeys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid master key: %s, %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid master key: %s, must be 32 bytes", id)
+		}
+		k.keys[id] = key
+	}
+
+	if len(k.keys) > 0 {
+		if _, ok := k.keys[k.current]; !ok {
+			return nil, fmt.Errorf("the current master key: %s is not one of the master keys", k.current)
+		}
+	}
+
+	return k, nil
+}
+
+func (k *Keyring) Enabled() bool {
+	return k != nil && len(k.keys) > 0
+}
+
+type sealed struct {
+	KeyID   string `json:"key_id"`
+	DataKey string `json:"data_key"`
+	Value   string `json:"value"`
+}
+
+// Encrypted returns the id of the master key the value was encrypted with, when it's been encrypted
+func Encrypted(v interface{}) (string, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	s, ok := m[Key].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	keyID, ok := s["key_id"].(string)
+	return keyID, ok
+}
+
+// Encrypt json encodes the value and returns it encrypted with a new data key under the current master key
+func (k *Keyring) Encrypt(v interface{}) (map[string]interface{}, error) {
+	if !k.Enabled() {
+		return nil, fmt.Errorf("encryption is not configured")
+	}
+
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	value, err := seal(dataKey, plaintext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := seal(k.keys[k.current], dataKey, []byte(k.current))
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		Key: map[string]interface{}{
+			"key_id":   k.current,
+			"data_key": base64.StdEncoding.EncodeToString(wrapped),
+			"value":    base64.StdEncoding.EncodeToString(value),
+		},
+	}, nil
+}
+
+// Decrypt returns the original value of an encrypted value, anything that isn't encrypted is returned as is
+func (k *Keyring) Decrypt(v interface{}) (interface{}, error) {
+	if _, ok := Encrypted(v); !ok {
+		return v, nil
+	}
+	if !k.Enabled() {
+		return nil, fmt.Errorf("encryption is not configured")
+	}
+
+	b, err := json.Marshal(v.(map[string]interface{})[Key])
+	if err != nil {
+		return nil, err
+	}
+	var s sealed
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+
+	masterKey, ok := k.keys[s.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key: %s", s.KeyID)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(s.DataKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := open(masterKey, wrapped, []byte(s.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap the data key: %w", err)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(s.Value)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(dataKey, value, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the value: %w", err)
+	}
+
+	var decrypted interface{}
+	if err := json.Unmarshal(plaintext, &decrypted); err != nil {
+		return nil, err
+	}
+
+	return decrypted, nil
+}
+
+// seal encrypts with AES-GCM, the nonce is prepended to the ciphertext
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	return gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
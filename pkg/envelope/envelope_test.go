@@ -0,0 +1,79 @@
+package envelope
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string([]byte{b}), 32)))
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	old, err := New(Config{
+		MetadataEncryptionKeys:  map[string]string{"old": testKey('a')},
+		MetadataEncryptionKeyID: "old",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := map[string]interface{}{"user": "app", "password": "hunter2"}
+	encrypted, err := old.Encrypt(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keyID, ok := Encrypted(encrypted); !ok || keyID != "old" {
+		t.Fatalf("Encrypted() = %s, %v", keyID, ok)
+	}
+
+	// rotated, the old key is only kept for decrypting
+	rotated, err := New(Config{
+		MetadataEncryptionKeys:  map[string]string{"old": testKey('a'), "new": testKey('b')},
+		MetadataEncryptionKeyID: "new",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := rotated.Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decrypted, value) {
+		t.Errorf("Decrypt() = %v, want %v", decrypted, value)
+	}
+
+	reencrypted, err := rotated.Encrypt(decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyID, _ := Encrypted(reencrypted); keyID != "new" {
+		t.Errorf("re-encrypted with: %s", keyID)
+	}
+
+	if _, err := old.Decrypt(reencrypted); err == nil {
+		t.Error("expected an error decrypting with an unknown master key")
+	}
+
+	plain, err := rotated.Decrypt("not encrypted")
+	if err != nil || plain != "not encrypted" {
+		t.Errorf("Decrypt() of a plain value = %v, %v", plain, err)
+	}
+}
+
+func TestNew_InvalidKeys(t *testing.T) {
+	if _, err := New(Config{MetadataEncryptionKeys: map[string]string{"a": "short"}, MetadataEncryptionKeyID: "a"}); err == nil {
+		t.Error("expected an error for a short key")
+	}
+	if _, err := New(Config{MetadataEncryptionKeys: map[string]string{"a": testKey('a')}, MetadataEncryptionKeyID: "b"}); err == nil {
+		t.Error("expected an error for a missing current key")
+	}
+	k, err := New(Config{})
+	if err != nil || k.Enabled() {
+		t.Errorf("New() with no keys = %v, %v", k.Enabled(), err)
+	}
+}
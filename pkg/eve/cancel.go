@@ -0,0 +1,18 @@
+package eve
+
+import (
+	"context"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// DeploymentCancel is the request used to stop a deployment that is still queued or scheduled
+type DeploymentCancel struct {
+	User string `json:"user"`
+}
+
+func (c DeploymentCancel) ValidateWithContext(ctx context.Context) error {
+	return validation.ValidateStructWithContext(ctx, &c,
+		validation.Field(&c.User, validation.Required),
+	)
+}
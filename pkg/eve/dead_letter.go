@@ -0,0 +1,42 @@
+package eve
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/unanet/eve/internal/data"
+)
+
+type DeadLetter struct {
+	ID           int        `json:"id"`
+	Queue        string     `json:"queue"`
+	MessageID    string     `json:"message_id"`
+	GroupID      string     `json:"group_id"`
+	Command      string     `json:"command"`
+	DeploymentID uuid.UUID  `json:"deployment_id,omitempty"`
+	Body         string     `json:"body"`
+	Error        string     `json:"error"`
+	ReceiveCount int        `json:"receive_count"`
+	ReplayedAt   *time.Time `json:"replayed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func ToDeadLetter(m data.DeadLetterMessage) DeadLetter {
+	deadLetter := DeadLetter{
+		ID:           m.ID,
+		Queue:        m.Queue,
+		MessageID:    m.MessageID,
+		GroupID:      m.GroupID,
+		Command:      m.Command,
+		DeploymentID: m.EveID.UUID,
+		Body:         m.Body,
+		Error:        m.Error,
+		ReceiveCount: m.ReceiveCount,
+		CreatedAt:    m.CreatedAt.Time,
+	}
+	if m.ReplayedAt.Valid {
+		deadLetter.ReplayedAt = &m.ReplayedAt.Time
+	}
+	return deadLetter
+}
@@ -0,0 +1,18 @@
+package eve
+
+import (
+	"time"
+)
+
+type DefinitionHistory struct {
+	Revision         int                    `json:"revision"`
+	DefinitionID     int                    `json:"definition_id"`
+	Description      string                 `json:"description"`
+	DefinitionTypeID int                    `json:"definition_type_id"`
+	Data             map[string]interface{} `json:"data"`
+	Changes          []HistoryChange        `json:"changes,omitempty"`
+	Created          time.Time              `json:"created"`
+	CreatedBy        string                 `json:"created_by"`
+	Deleted          *time.Time             `json:"deleted"`
+	DeletedBy        *string                `json:"deleted_by"`
+}
@@ -1,6 +1,7 @@
 package eve
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -34,6 +35,10 @@ const (
 	DeploymentStateQueued    DeploymentState = "queued"
 	DeploymentStateScheduled DeploymentState = "scheduled"
 	DeploymentStateCompleted DeploymentState = "completed"
+	DeploymentStateFailed    DeploymentState = "failed"
+	DeploymentStatePartial   DeploymentState = "partial"
+	DeploymentStateCancelled DeploymentState = "cancelled"
+	DeploymentStateTimedOut  DeploymentState = "timed_out"
 	DeploymentStateUnknown   DeploymentState = "unknown"
 )
 
@@ -45,11 +50,38 @@ func ParseDeploymentState(value data.DeploymentState) DeploymentState {
 		return DeploymentStateScheduled
 	case data.DeploymentStateCompleted:
 		return DeploymentStateCompleted
+	case data.DeploymentStateFailed:
+		return DeploymentStateFailed
+	case data.DeploymentStatePartial:
+		return DeploymentStatePartial
+	case data.DeploymentStateCancelled:
+		return DeploymentStateCancelled
+	case data.DeploymentStateTimedOut:
+		return DeploymentStateTimedOut
 	default:
 		return DeploymentStateUnknown
 	}
 }
 
+func ToDataDeploymentState(value DeploymentState) data.DeploymentState {
+	switch value {
+	case DeploymentStateQueued:
+		return data.DeploymentStateQueued
+	case DeploymentStateScheduled:
+		return data.DeploymentStateScheduled
+	case DeploymentStateFailed:
+		return data.DeploymentStateFailed
+	case DeploymentStatePartial:
+		return data.DeploymentStatePartial
+	case DeploymentStateCancelled:
+		return data.DeploymentStateCancelled
+	case DeploymentStateTimedOut:
+		return data.DeploymentStateTimedOut
+	default:
+		return data.DeploymentStateCompleted
+	}
+}
+
 const (
 	DeploymentPlanTypeApplication PlanType = "application"
 	DeploymentPlanTypeJob         PlanType = "job"
@@ -103,6 +135,7 @@ type DeployArtifact struct {
 	AvailableVersion    string               `json:"available_version"`
 	ImageTag            string               `json:"image_tag"`
 	Metadata            MetadataField        `json:"metadata"`
+	Secrets             []string             `json:"secrets,omitempty"`
 	ArtifactoryFeed     string               `json:"artifactory_feed"`
 	ArtifactoryPath     string               `json:"artifactory_path"`
 	ArtifactoryFeedType string               `json:"artifactory_feed_type"`
@@ -141,6 +174,44 @@ type DeploymentSpec interface {
 	Metrics() string
 }
 
+func (da *DeployArtifact) masked() *DeployArtifact {
+	if da == nil || len(da.Secrets) == 0 {
+		return da
+	}
+	masked := *da
+	masked.Metadata = MaskSecrets(da.Metadata, da.Secrets)
+	return &masked
+}
+
+func (da *DeployArtifact) secretValues() map[string]interface{} {
+	if da == nil || len(da.Secrets) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(da.Secrets))
+	for _, pointer := range da.Secrets {
+		replaceSecret(da.Metadata, pointer, func(v interface{}) interface{} {
+			values[pointer] = v
+			return v
+		})
+	}
+	return values
+}
+
+func (da *DeployArtifact) setSecretValues(values map[string]interface{}) {
+	if da == nil {
+		return
+	}
+	for _, pointer := range da.Secrets {
+		v, ok := values[pointer]
+		if !ok {
+			continue
+		}
+		replaceSecret(da.Metadata, pointer, func(interface{}) interface{} {
+			return v
+		})
+	}
+}
+
 type DeployService struct {
 	*DeployArtifact
 	ServiceID        int    `json:"service_id"`
@@ -349,8 +420,79 @@ type NSDeploymentPlan struct {
 	SchQueueUrl       string               `json:"-"`
 	CallbackURL       string               `json:"callback_url"`
 	Status            DeploymentPlanStatus `json:"status"`
+	State             DeploymentState      `json:"state,omitempty"`
 	MetadataOverrides MetadataField        `json:"metadata_overrides"`
 	Type              PlanType             `json:"type"`
+	TraceContext      queue.TraceContext   `json:"trace_context,omitempty"`
+}
+
+// Masked returns a copy of the plan with the secrets in the metadata masked, it's what anything other than
+// the scheduler gets to see
+func (ns *NSDeploymentPlan) Masked() *NSDeploymentPlan {
+	masked := *ns
+	masked.Services = nil
+	for _, x := range ns.Services {
+		service := *x
+		service.DeployArtifact = x.DeployArtifact.masked()
+		masked.Services = append(masked.Services, &service)
+	}
+	masked.Jobs = nil
+	for _, x := range ns.Jobs {
+		job := *x
+		job.DeployArtifact = x.DeployArtifact.masked()
+		masked.Jobs = append(masked.Jobs, &job)
+	}
+	return &masked
+}
+
+// PlanSecrets are the resolved secrets of a plan's services and jobs, keyed by their id and the JSON pointer of the
+// secret in their metadata
+type PlanSecrets struct {
+	Services map[int]map[string]interface{} `json:"services,omitempty"`
+	Jobs     map[int]map[string]interface{} `json:"jobs,omitempty"`
+}
+
+// Secrets returns the resolved secrets in the plan's metadata, it's nil when there aren't any
+func (ns *NSDeploymentPlan) Secrets() *PlanSecrets {
+	var secrets PlanSecrets
+	for _, x := range ns.Services {
+		if values := x.DeployArtifact.secretValues(); len(values) > 0 {
+			if secrets.Services == nil {
+				secrets.Services = make(map[int]map[string]interface{})
+			}
+			secrets.Services[x.ServiceID] = values
+		}
+	}
+	for _, x := range ns.Jobs {
+		if values := x.DeployArtifact.secretValues(); len(values) > 0 {
+			if secrets.Jobs == nil {
+				secrets.Jobs = make(map[int]map[string]interface{})
+			}
+			secrets.Jobs[x.JobID] = values
+		}
+	}
+	if secrets.Services == nil && secrets.Jobs == nil {
+		return nil
+	}
+	return &secrets
+}
+
+// SetSecrets puts the resolved secrets back into the plan's (masked) metadata
+func (ns *NSDeploymentPlan) SetSecrets(secrets *PlanSecrets) {
+	if secrets == nil {
+		return
+	}
+	for _, x := range ns.Services {
+		x.DeployArtifact.setSecretValues(secrets.Services[x.ServiceID])
+	}
+	for _, x := range ns.Jobs {
+		x.DeployArtifact.setSecretValues(secrets.Jobs[x.JobID])
+	}
+}
+
+// ContextWithTrace returns ctx continuing the trace the plan was scheduled in, for the scheduler to pick up
+func (ns *NSDeploymentPlan) ContextWithTrace(ctx context.Context) context.Context {
+	return queue.ExtractTraceContext(ctx, ns.TraceContext)
 }
 
 // DeploymentPlanType is a helper method to know what type of deployment plan (application,job,migration,restart)
@@ -397,12 +539,43 @@ func (ns *NSDeploymentPlan) Failed() bool {
 	return false
 }
 
+func (ns *NSDeploymentPlan) succeeded() bool {
+	for _, x := range ns.Services {
+		if x.Result == DeployArtifactResultSuccess {
+			return true
+		}
+	}
+
+	for _, x := range ns.Jobs {
+		if x.Result == DeployArtifactResultSuccess {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Outcome is the terminal state of the deployment once the scheduler has reported back the result of each artifact.
+// Anything that succeeded next to a failure or an artifact that never got deployed (noop) is partial
+func (ns *NSDeploymentPlan) Outcome() DeploymentState {
+	switch {
+	case ns.Failed() && ns.succeeded():
+		return DeploymentStatePartial
+	case ns.Failed():
+		return DeploymentStateFailed
+	case ns.NoopExist() && ns.succeeded():
+		return DeploymentStatePartial
+	default:
+		return DeploymentStateCompleted
+	}
+}
+
 func (ns *NSDeploymentPlan) Message(format string, a ...interface{}) {
 	ns.Messages = append(ns.Messages, fmt.Sprintf(format, a...))
 }
 
 func ToDeployment(d data.Deployment) Deployment {
-	return Deployment{
+	deployment := Deployment{
 		ID:            d.ID,
 		EnvironmentID: d.EnvironmentID,
 		NamespaceID:   d.NamespaceID,
@@ -411,16 +584,66 @@ func ToDeployment(d data.Deployment) Deployment {
 		CreatedAt:     d.CreatedAt.Time,
 		UpdatedAt:     d.UpdatedAt.Time,
 	}
+
+	if d.RollbackOf.Valid {
+		deployment.RollbackOf = &d.RollbackOf.UUID
+	}
+
+	return deployment
 }
 
 type Deployment struct {
-	ID            uuid.UUID       `json:"id"`
-	EnvironmentID int             `json:"environment_id"`
-	NamespaceID   int             `json:"namespace_id"`
-	User          string          `json:"user"`
-	State         DeploymentState `json:"state"`
-	CreatedAt     time.Time       `json:"created_at"`
-	UpdatedAt     time.Time       `json:"updated_at"`
+	ID            uuid.UUID           `json:"id"`
+	EnvironmentID int                 `json:"environment_id"`
+	NamespaceID   int                 `json:"namespace_id"`
+	User          string              `json:"user"`
+	State         DeploymentState     `json:"state"`
+	RollbackOf    *uuid.UUID          `json:"rollback_of,omitempty"`
+	Results       []DeploymentResult  `json:"results,omitempty"`
+	Messages      []DeploymentMessage `json:"messages,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// DeploymentResult is what the scheduler reported back for a single service/job in a deployment
+type DeploymentResult struct {
+	ServiceID        int                  `json:"service_id,omitempty"`
+	JobID            int                  `json:"job_id,omitempty"`
+	Name             string               `json:"name"`
+	RequestedVersion string               `json:"requested_version"`
+	DeployedVersion  string               `json:"deployed_version"`
+	AvailableVersion string               `json:"available_version"`
+	Result           DeployArtifactResult `json:"result"`
+	ExitCode         int                  `json:"exit_code"`
+	CreatedAt        time.Time            `json:"created_at"`
+}
+
+type DeploymentMessage struct {
+	Source    string    `json:"source"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DeploymentQuery filters the deployment listing, every field is optional.
+// Environment and Namespace accept either an id or a name
+type DeploymentQuery struct {
+	Environment string
+	Namespace   string
+	User        string
+	State       string
+	Type        string
+	Artifact    string
+	CronID      string
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+	Cursor      string
+	Ascending   bool
+}
+
+type DeploymentPage struct {
+	Deployments []Deployment `json:"deployments"`
+	NextCursor  string       `json:"next_cursor,omitempty"`
 }
 
 type DeploymentCronJob struct {
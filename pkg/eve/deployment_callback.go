@@ -0,0 +1,79 @@
+package eve
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/unanet/go/pkg/json"
+
+	"github.com/unanet/eve/internal/data"
+)
+
+type DeploymentCallbackState string
+
+const (
+	DeploymentCallbackStatePending   DeploymentCallbackState = "pending"
+	DeploymentCallbackStateDelivered DeploymentCallbackState = "delivered"
+	DeploymentCallbackStateFailed    DeploymentCallbackState = "failed"
+)
+
+// DeploymentCallbackDelivery is a single attempt at posting a callback, StatusCode is left off when the request never got a response
+type DeploymentCallbackDelivery struct {
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ElapsedMs  int       `json:"elapsed_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type DeploymentCallback struct {
+	ID             int                          `json:"id"`
+	DeploymentID   *uuid.UUID                   `json:"deployment_id,omitempty"`
+	SubscriptionID int                          `json:"subscription_id,omitempty"`
+	Event          EventType                    `json:"event,omitempty"`
+	URL            string                       `json:"url"`
+	Body           json.Object                  `json:"body"`
+	State          DeploymentCallbackState      `json:"state"`
+	Attempts       int                          `json:"attempts"`
+	NextAttemptAt  *time.Time                   `json:"next_attempt_at,omitempty"`
+	LastStatusCode int                          `json:"last_status_code,omitempty"`
+	LastError      string                       `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time                   `json:"delivered_at,omitempty"`
+	Deliveries     []DeploymentCallbackDelivery `json:"deliveries,omitempty"`
+	CreatedAt      time.Time                    `json:"created_at"`
+	UpdatedAt      time.Time                    `json:"updated_at"`
+}
+
+func ToDeploymentCallback(c data.DeploymentCallback) DeploymentCallback {
+	callback := DeploymentCallback{
+		ID:             c.ID,
+		SubscriptionID: int(c.SubscriptionID.Int32),
+		Event:          EventType(c.Event.String),
+		URL:            c.URL,
+		Body:           c.Body,
+		State:          DeploymentCallbackState(c.State),
+		Attempts:       c.Attempts,
+		LastStatusCode: int(c.LastStatusCode.Int32),
+		LastError:      c.LastError.String,
+		CreatedAt:      c.CreatedAt.Time,
+		UpdatedAt:      c.UpdatedAt.Time,
+	}
+	if c.DeploymentID.Valid {
+		callback.DeploymentID = &c.DeploymentID.UUID
+	}
+	if c.State == data.DeploymentCallbackStatePending && c.NextAttemptAt.Valid {
+		callback.NextAttemptAt = &c.NextAttemptAt.Time
+	}
+	if c.DeliveredAt.Valid {
+		callback.DeliveredAt = &c.DeliveredAt.Time
+	}
+	return callback
+}
+
+func ToDeploymentCallbackDelivery(a data.DeploymentCallbackAttempt) DeploymentCallbackDelivery {
+	return DeploymentCallbackDelivery{
+		StatusCode: int(a.StatusCode.Int32),
+		Error:      a.Error.String,
+		ElapsedMs:  a.ElapsedMs,
+		CreatedAt:  a.CreatedAt.Time,
+	}
+}
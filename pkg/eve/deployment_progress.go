@@ -0,0 +1,35 @@
+package eve
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type DeploymentProgressType string
+
+const (
+	// DeploymentProgressPlan is sent once the plan has been sent to the scheduler
+	DeploymentProgressPlan DeploymentProgressType = "plan"
+	// DeploymentProgressMessage carries the messages the scheduler sends while it's deploying
+	DeploymentProgressMessage DeploymentProgressType = "message"
+	// DeploymentProgressResult is sent for every service and job once the scheduler is done with them
+	DeploymentProgressResult DeploymentProgressType = "result"
+	// DeploymentProgressComplete is always the last event for a deployment
+	DeploymentProgressComplete DeploymentProgressType = "complete"
+)
+
+// DeploymentProgress is a single event on the deployment and environment event streams
+type DeploymentProgress struct {
+	Type         DeploymentProgressType `json:"type"`
+	DeploymentID uuid.UUID              `json:"deployment_id"`
+	Environment  string                 `json:"environment"`
+	Namespace    string                 `json:"namespace"`
+	State        DeploymentState        `json:"state,omitempty"`
+	Artifacts    []DeploymentResult     `json:"artifacts,omitempty"`
+	Result       *DeploymentResult      `json:"result,omitempty"`
+	Messages     []string               `json:"messages,omitempty"`
+	// Truncated is set when the artifacts or messages didn't fit in the event, the deployment has all of them
+	Truncated bool      `json:"truncated,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
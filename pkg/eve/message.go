@@ -6,21 +6,22 @@ import (
 	"fmt"
 
 	uuid "github.com/satori/go.uuid"
-	"github.com/unanet/eve/pkg/s3"
+	"github.com/unanet/eve/pkg/storage"
 	"github.com/unanet/go/pkg/errors"
 )
 
 type CloudUploader interface {
-	Upload(ctx context.Context, key string, body []byte) (*s3.Location, error)
+	Upload(ctx context.Context, key string, body []byte) (*storage.Location, error)
 }
 
 type CloudDownloader interface {
-	Download(ctx context.Context, location *s3.Location) ([]byte, error)
+	Download(ctx context.Context, location *storage.Location) ([]byte, error)
 }
 
 type DeploymentCallbackMessage struct {
 	DeploymentID uuid.UUID            `json:"deployment_id"`
 	Status       DeploymentPlanStatus `json:"status"`
+	State        DeploymentState      `json:"state,omitempty"`
 	Type         PlanType             `json:"type"`
 	Messages     []string             `json:"messages"`
 }
@@ -29,14 +30,24 @@ type CallbackMessage struct {
 	Messages []string `json:"messages"`
 }
 
+// planLocation is the body of a plan message. The stored plan has its secrets masked, the resolved secrets only
+// travel in the message to the scheduler.
+//
+// Breaking change: a scheduler reading the message with a version of this package from before the secrets were added
+// ignores them and deploys the masked values, it has to be upgraded before metadata uses secret references
+type planLocation struct {
+	storage.Location
+	Secrets *PlanSecrets `json:"secrets,omitempty"`
+}
+
 func UnMarshalNSDeploymentFromS3LocationBody(ctx context.Context, cd CloudDownloader, b []byte) (*NSDeploymentPlan, error) {
-	var location s3.Location
+	var location planLocation
 	err := json.Unmarshal(b, &location)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
 
-	planText, err := cd.Download(ctx, &location)
+	planText, err := cd.Download(ctx, &location.Location)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
@@ -46,12 +57,15 @@ func UnMarshalNSDeploymentFromS3LocationBody(ctx context.Context, cd CloudDownlo
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
+	nsDeploymentPlan.SetSecrets(location.Secrets)
 
 	return &nsDeploymentPlan, nil
 }
 
+// MarshalNSDeploymentPlanToS3LocationBody uploads the plan with its secrets masked and returns its location, it's
+// what's safe to store. PlanMessageBody adds the secrets for the message to the scheduler
 func MarshalNSDeploymentPlanToS3LocationBody(ctx context.Context, cu CloudUploader, plan *NSDeploymentPlan) ([]byte, error) {
-	nsDeploymentJson, err := json.Marshal(plan)
+	nsDeploymentJson, err := json.Marshal(plan.Masked())
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
@@ -67,3 +81,25 @@ func MarshalNSDeploymentPlanToS3LocationBody(ctx context.Context, cu CloudUpload
 
 	return locationJson, nil
 }
+
+// PlanMessageBody returns the location body with the plan's resolved secrets added, it must only be sent in a message
+// and never stored
+func PlanMessageBody(locationBody []byte, plan *NSDeploymentPlan) ([]byte, error) {
+	secrets := plan.Secrets()
+	if secrets == nil {
+		return locationBody, nil
+	}
+
+	var location planLocation
+	err := json.Unmarshal(locationBody, &location)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	location.Secrets = secrets
+
+	body, err := json.Marshal(location)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return body, nil
+}
@@ -5,11 +5,29 @@ import (
 )
 
 type MetadataHistory struct {
+	Revision    int                    `json:"revision"`
 	MetadataId  int                    `json:"metadata_id"`
 	Description string                 `json:"description"`
 	Value       map[string]interface{} `json:"value"`
+	Changes     []HistoryChange        `json:"changes,omitempty"`
 	Created     time.Time              `json:"created"`
 	CreatedBy   string                 `json:"created_by"`
 	Deleted     *time.Time             `json:"deleted"`
 	DeletedBy   *string                `json:"deleted_by"`
 }
+
+type HistoryChangeType string
+
+const (
+	HistoryChangeAdded   HistoryChangeType = "added"
+	HistoryChangeRemoved HistoryChangeType = "removed"
+	HistoryChangeChanged HistoryChangeType = "changed"
+)
+
+// HistoryChange is a single top level key that differs between a revision and the one before it
+type HistoryChange struct {
+	Key    string            `json:"key"`
+	Type   HistoryChangeType `json:"type"`
+	Before interface{}       `json:"before,omitempty"`
+	After  interface{}       `json:"after,omitempty"`
+}
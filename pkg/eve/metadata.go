@@ -19,15 +19,21 @@ func (m MetadataField) ValidateWithContext(ctx context.Context) error {
 		return validation.NewError("400", "cannot have an empty value as a key for metadata")
 	}
 
+	if err := validateSecretReferences(map[string]interface{}(m)); err != nil {
+		return validation.NewError("400", err.Error())
+	}
+
 	return nil
 }
 
+// Metadata values for the EncryptedKeys are encrypted at rest, they're only decrypted when a plan is built
 type Metadata struct {
-	ID          int           `json:"id"`
-	Description string        `json:"description"`
-	Value       MetadataField `json:"value"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
+	ID            int           `json:"id"`
+	Description   string        `json:"description"`
+	Value         MetadataField `json:"value"`
+	EncryptedKeys StringList    `json:"encrypted_keys,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
 func (m Metadata) ValidateWithContext(ctx context.Context) error {
@@ -171,3 +177,27 @@ func (m MetadataJobMap) ValidateWithContext(ctx context.Context) error {
 			return nil
 		})))
 }
+
+// MetadataSource is one metadata map's contribution to an effective metadata key
+type MetadataSource struct {
+	MetadataID          int         `json:"metadata_id"`
+	MetadataDescription string      `json:"metadata_description"`
+	MapDescription      string      `json:"map_description"`
+	Scope               string      `json:"scope"`
+	StackingOrder       int         `json:"stacking_order"`
+	Value               interface{} `json:"value"`
+}
+
+// MetadataKeyExplanation is an effective metadata key along with the map it came from and the values from lower
+// stacking orders that it was merged over
+type MetadataKeyExplanation struct {
+	Key      string           `json:"key"`
+	Value    interface{}      `json:"value"`
+	Source   MetadataSource   `json:"source"`
+	Overrode []MetadataSource `json:"overrode,omitempty"`
+}
+
+type MetadataExplanation struct {
+	Metadata MetadataField            `json:"metadata"`
+	Keys     []MetadataKeyExplanation `json:"keys"`
+}
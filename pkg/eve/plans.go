@@ -116,6 +116,7 @@ type DeploymentPlanOptions struct {
 	Type             PlanType            `json:"type"`
 	DeploymentIDs    []uuid.UUID         `json:"deployment_ids,omitempty"`
 	Metadata         MetadataField       `json:"metadata"`
+	RollbackOf       *uuid.UUID          `json:"rollback_of,omitempty"`
 }
 
 func (po *DeploymentPlanOptions) PlanType() string {
@@ -134,6 +135,16 @@ func (po DeploymentPlanOptions) HasNamespaceAliases() bool {
 	return len(po.NamespaceAliases) > 0
 }
 
+func (po DeploymentPlanOptions) IsRollback() bool {
+	return po.RollbackOf != nil
+}
+
+// PinsVersions is true when the requested versions are exact, a rollback puts back what was there and a restart keeps
+// what's deployed, so neither may resolve a wildcard to a newer build
+func (po DeploymentPlanOptions) PinsVersions() bool {
+	return po.IsRollback() || po.Type == DeploymentPlanTypeRestart
+}
+
 func (po DeploymentPlanOptions) ValidateWithContext(ctx context.Context) error {
 	return validation.ValidateStructWithContext(ctx, &po,
 		validation.Field(&po.Environment, validation.Required),
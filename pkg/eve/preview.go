@@ -0,0 +1,23 @@
+package eve
+
+// MetadataPreview is a service or job's effective metadata before and after a proposed metadata map is saved
+type MetadataPreview struct {
+	ServiceID     int           `json:"service_id,omitempty"`
+	JobID         int           `json:"job_id,omitempty"`
+	Name          string        `json:"name"`
+	NamespaceName string        `json:"namespace_name"`
+	Changed       bool          `json:"changed"`
+	Before        MetadataField `json:"before"`
+	After         MetadataField `json:"after"`
+}
+
+// DefinitionPreview is a service or job's merged definitions before and after a proposed definition map is saved
+type DefinitionPreview struct {
+	ServiceID     int               `json:"service_id,omitempty"`
+	JobID         int               `json:"job_id,omitempty"`
+	Name          string            `json:"name"`
+	NamespaceName string            `json:"namespace_name"`
+	Changed       bool              `json:"changed"`
+	Before        DefinitionResults `json:"before"`
+	After         DefinitionResults `json:"after"`
+}
@@ -19,3 +19,18 @@ func (r RestartService) ValidateWithContext(ctx context.Context) error {
 		validation.Field(&r.Environment, validation.Required),
 	)
 }
+
+// ServiceRestart is the request used to restart services at the version they are already running.
+// Services is ignored when the service is already part of the route
+type ServiceRestart struct {
+	User        string           `json:"user"`
+	CallbackURL string           `json:"callback_url"`
+	Services    []RestartService `json:"services,omitempty"`
+}
+
+func (r ServiceRestart) ValidateWithContext(ctx context.Context) error {
+	return validation.ValidateStructWithContext(ctx, &r,
+		validation.Field(&r.User, validation.Required),
+		validation.Field(&r.Services),
+	)
+}
@@ -0,0 +1,20 @@
+package eve
+
+import (
+	"context"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// DeploymentRollback is the request used to redeploy the versions that a previous deployment replaced
+type DeploymentRollback struct {
+	User        string `json:"user"`
+	CallbackURL string `json:"callback_url"`
+	DryRun      bool   `json:"dry_run"`
+}
+
+func (r DeploymentRollback) ValidateWithContext(ctx context.Context) error {
+	return validation.ValidateStructWithContext(ctx, &r,
+		validation.Field(&r.User, validation.Required),
+	)
+}
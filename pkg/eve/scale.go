@@ -0,0 +1,48 @@
+package eve
+
+import (
+	"context"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// ServiceScale is the request used to change the replica count of one or more services.
+// Services is only used by the namespace/environment variants to limit which services get scaled
+type ServiceScale struct {
+	Count       *int       `json:"count"`
+	User        string     `json:"user"`
+	Restart     bool       `json:"restart"`
+	CallbackURL string     `json:"callback_url"`
+	Services    StringList `json:"services,omitempty"`
+}
+
+func (s ServiceScale) ValidateWithContext(ctx context.Context) error {
+	return validation.ValidateStructWithContext(ctx, &s,
+		validation.Field(&s.Count, validation.NotNil),
+		validation.Field(&s.User, validation.Required),
+	)
+}
+
+type ScaledService struct {
+	ServiceID     int    `json:"service_id"`
+	Name          string `json:"name"`
+	NamespaceName string `json:"namespace_name"`
+	FromCount     int    `json:"from_count"`
+	Count         int    `json:"count"`
+}
+
+type ServiceScaleResult struct {
+	Services []ScaledService          `json:"services"`
+	Restarts []*DeploymentPlanOptions `json:"restarts,omitempty"`
+}
+
+// ScaleHistory is a recorded change to the replica count of a service
+type ScaleHistory struct {
+	ID        int       `json:"id"`
+	ServiceID int       `json:"service_id"`
+	FromCount int       `json:"from_count"`
+	Count     int       `json:"count"`
+	User      string    `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,139 @@
+package eve
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SecretReferenceKey marks a metadata value as a reference to a secret, e.g. {"$secret": "vault://secret/app#password"}.
+	// Only the reference is stored, in metadata and metadata_history, so the read APIs never return a secret. It's
+	// resolved into the plan the scheduler receives, the stored plan and everything else shown has it masked
+	SecretReferenceKey = "$secret"
+	SecretMask         = "********"
+)
+
+// SecretReference returns the reference when the value is a secret reference
+func SecretReference(v interface{}) (string, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	ref, ok := m[SecretReferenceKey].(string)
+	return ref, ok
+}
+
+// validateSecretReferences makes sure every secret reference in the value is well formed, a reference with anything
+// else alongside it would have that stored in plain text
+func validateSecretReferences(v interface{}) error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := t[SecretReferenceKey]; ok {
+			s, ok := ref.(string)
+			if !ok || len(t) != 1 {
+				return errors.New("a secret reference must be an object with a single $secret string value")
+			}
+			u, err := url.Parse(s)
+			if err != nil || len(u.Scheme) == 0 || len(u.Host)+len(u.Path)+len(u.Opaque) == 0 {
+				return fmt.Errorf("invalid secret reference: %s", s)
+			}
+			return nil
+		}
+		for _, x := range t {
+			if err := validateSecretReferences(x); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, x := range t {
+			if err := validateSecretReferences(x); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SecretPointer appends a key or array index to a JSON pointer (RFC 6901)
+func SecretPointer(pointer string, key string) string {
+	return pointer + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// MaskSecrets returns a copy of the metadata with the values at the JSON pointers replaced by SecretMask
+func MaskSecrets(m MetadataField, pointers []string) MetadataField {
+	if len(pointers) == 0 {
+		return m
+	}
+
+	masked := copyMetadataValue(map[string]interface{}(m)).(map[string]interface{})
+	for _, pointer := range pointers {
+		replaceSecret(masked, pointer, func(interface{}) interface{} {
+			return SecretMask
+		})
+	}
+
+	return masked
+}
+
+// replaceSecret replaces the value at the JSON pointer with what replace returns for it, nothing is replaced when
+// the pointer doesn't match a value
+func replaceSecret(root interface{}, pointer string, replace func(interface{}) interface{}) {
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	parts := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	current := root
+	if m, ok := root.(MetadataField); ok {
+		current = map[string]interface{}(m)
+	}
+	for i, part := range parts {
+		part = unescape.Replace(part)
+		last := i == len(parts)-1
+		switch t := current.(type) {
+		case map[string]interface{}:
+			v, ok := t[part]
+			if !ok {
+				return
+			}
+			if last {
+				t[part] = replace(v)
+				return
+			}
+			current = v
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(t) {
+				return
+			}
+			if last {
+				t[index] = replace(t[index])
+				return
+			}
+			current = t[index]
+		default:
+			return
+		}
+	}
+}
+
+func copyMetadataValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case MetadataField:
+		return copyMetadataValue(map[string]interface{}(t))
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(t))
+		for k, x := range t {
+			c[k] = copyMetadataValue(x)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(t))
+		for i, x := range t {
+			c[i] = copyMetadataValue(x)
+		}
+		return c
+	default:
+		return v
+	}
+}
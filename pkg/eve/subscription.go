@@ -0,0 +1,115 @@
+package eve
+
+import (
+	"context"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	uuid "github.com/satori/go.uuid"
+)
+
+type EventType string
+
+const (
+	EventDeploymentQueued    EventType = "deployment.queued"
+	EventDeploymentScheduled EventType = "deployment.scheduled"
+	EventDeploymentCompleted EventType = "deployment.completed"
+	EventDeploymentFailed    EventType = "deployment.failed"
+	EventDeploymentPartial   EventType = "deployment.partial"
+	EventDeploymentCancelled EventType = "deployment.cancelled"
+	EventDeploymentTimedOut  EventType = "deployment.timed_out"
+	EventReleasePromoted     EventType = "release.promoted"
+	EventMetadataChanged     EventType = "metadata.changed"
+	EventCronFired           EventType = "cron.fired"
+)
+
+// DeploymentEventType returns the event sent when a deployment reaches the state
+func DeploymentEventType(state DeploymentState) EventType {
+	return EventType("deployment." + string(state))
+}
+
+// Event is the body posted to every subscription that matches it, Data holds the event specific payload
+type Event struct {
+	ID           uuid.UUID   `json:"id"`
+	Type         EventType   `json:"type"`
+	Environment  string      `json:"environment,omitempty"`
+	Namespace    string      `json:"namespace,omitempty"`
+	Artifacts    StringList  `json:"artifacts,omitempty"`
+	DeploymentID *uuid.UUID  `json:"deployment_id,omitempty"`
+	Data         interface{} `json:"data,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+type Subscription struct {
+	ID          int         `json:"id"`
+	Name        string      `json:"name"`
+	URL         string      `json:"url"`
+	Secret      string      `json:"secret,omitempty"`
+	Events      []EventType `json:"events"`
+	Environment string      `json:"environment,omitempty"`
+	Namespace   string      `json:"namespace,omitempty"`
+	Artifact    string      `json:"artifact,omitempty"`
+	Disabled    bool        `json:"disabled"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+func (s Subscription) ValidateWithContext(ctx context.Context) error {
+	return validation.ValidateStructWithContext(ctx, &s,
+		validation.Field(&s.Name, validation.Required),
+		validation.Field(&s.URL, validation.Required, is.URL),
+		validation.Field(&s.Events, validation.Required, validation.Each(validation.In(
+			EventDeploymentQueued,
+			EventDeploymentScheduled,
+			EventDeploymentCompleted,
+			EventDeploymentFailed,
+			EventDeploymentPartial,
+			EventDeploymentCancelled,
+			EventDeploymentTimedOut,
+			EventReleasePromoted,
+			EventMetadataChanged,
+			EventCronFired,
+		))),
+	)
+}
+
+// Matches reports whether the event should be sent to the subscription. A filter that's set only matches events that
+// carry the same environment, namespace or artifact, so an environment filter never matches a metadata change
+func (s Subscription) Matches(e Event) bool {
+	if s.Disabled {
+		return false
+	}
+
+	subscribed := false
+	for _, x := range s.Events {
+		if x == e.Type {
+			subscribed = true
+			break
+		}
+	}
+	if !subscribed {
+		return false
+	}
+
+	if len(s.Environment) > 0 && s.Environment != e.Environment {
+		return false
+	}
+
+	if len(s.Namespace) > 0 && s.Namespace != e.Namespace {
+		return false
+	}
+
+	if len(s.Artifact) > 0 && !e.Artifacts.Contains(s.Artifact) {
+		return false
+	}
+
+	return true
+}
+
+// DeploymentCronFired is the data of a cron.fired event
+type DeploymentCronFired struct {
+	DeploymentCronID uuid.UUID   `json:"deployment_cron_id"`
+	Description      string      `json:"description"`
+	DeploymentIDs    []uuid.UUID `json:"deployment_ids"`
+}
@@ -0,0 +1,35 @@
+package eve
+
+import "testing"
+
+func TestSubscription_Matches(t *testing.T) {
+	subscription := Subscription{
+		Events:      []EventType{EventDeploymentCompleted, EventDeploymentFailed},
+		Environment: "prod",
+		Artifact:    "api",
+	}
+
+	tests := []struct {
+		name  string
+		event Event
+		want  bool
+	}{
+		{"matches", Event{Type: EventDeploymentFailed, Environment: "prod", Artifacts: StringList{"web", "api"}}, true},
+		{"event not subscribed", Event{Type: EventDeploymentQueued, Environment: "prod", Artifacts: StringList{"api"}}, false},
+		{"other environment", Event{Type: EventDeploymentCompleted, Environment: "qa", Artifacts: StringList{"api"}}, false},
+		{"other artifact", Event{Type: EventDeploymentCompleted, Environment: "prod", Artifacts: StringList{"web"}}, false},
+		{"no environment on the event", Event{Type: EventDeploymentCompleted, Artifacts: StringList{"api"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subscription.Matches(tt.event); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	subscription.Disabled = true
+	if subscription.Matches(tests[0].event) {
+		t.Error("a disabled subscription should never match")
+	}
+}
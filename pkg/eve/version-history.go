@@ -0,0 +1,31 @@
+package eve
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type VersionHistory struct {
+	ID            int                  `json:"id"`
+	DeploymentID  uuid.UUID            `json:"deployment_id"`
+	EnvironmentID int                  `json:"environment_id"`
+	NamespaceID   int                  `json:"namespace_id"`
+	ServiceID     int                  `json:"service_id,omitempty"`
+	JobID         int                  `json:"job_id,omitempty"`
+	Name          string               `json:"name"`
+	FromVersion   string               `json:"from_version"`
+	ToVersion     string               `json:"to_version"`
+	Result        DeployArtifactResult `json:"result"`
+	ExitCode      int                  `json:"exit_code"`
+	User          string               `json:"user"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+// HistoryQuery pages through history newest first, From is inclusive and To is exclusive
+type HistoryQuery struct {
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/unanet/go/pkg/errors"
+)
+
+type sendMailFunc func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// Email sends a plain text mail through the configured smtp server
+type Email struct {
+	addr     string
+	auth     smtp.Auth
+	from     string
+	to       []string
+	timeout  time.Duration
+	sendMail sendMailFunc
+}
+
+func NewEmail(cfg Config, to []string) *Email {
+	var auth smtp.Auth
+	if len(cfg.SMTPUsername) > 0 {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	e := &Email{
+		addr:    fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort),
+		auth:    auth,
+		from:    cfg.SMTPFrom,
+		to:      to,
+		timeout: cfg.NotifyTimeout,
+	}
+	e.sendMail = e.dialAndSend
+	return e
+}
+
+func (e *Email) Send(ctx context.Context, n Notification) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", n.Title())
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&b, "Deployment: %s\r\n", n.DeploymentID)
+	fmt.Fprintf(&b, "Type: %s\r\n\r\n", n.Type)
+	for _, x := range n.Lines() {
+		b.WriteString(x + "\r\n")
+	}
+	if len(n.Messages) > 0 {
+		b.WriteString("\r\nMessages:\r\n")
+		for _, x := range n.Messages {
+			b.WriteString(x + "\r\n")
+		}
+	}
+
+	err := e.sendMail(ctx, e.addr, e.auth, e.from, e.to, []byte(b.String()))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	return nil
+}
+
+// dialAndSend is smtp.SendMail with a dial timeout, the connection is bound to the deadline of ctx since net/smtp
+// doesn't take a context
+func (e *Email) dialAndSend(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	dialer := net.Dialer{Timeout: e.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err = c.Auth(a); err != nil {
+				return errors.Wrap(err)
+			}
+		}
+	}
+
+	if err = c.Mail(from); err != nil {
+		return errors.Wrap(err)
+	}
+	for _, x := range to {
+		if err = c.Rcpt(x); err != nil {
+			return errors.Wrap(err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	if _, err = w.Write(msg); err != nil {
+		return errors.Wrap(err)
+	}
+	if err = w.Close(); err != nil {
+		return errors.Wrap(err)
+	}
+	return errors.Wrap(c.Quit())
+}
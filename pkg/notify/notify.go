@@ -0,0 +1,256 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/log"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+const (
+	SinkSlack = "slack"
+	SinkTeams = "teams"
+	SinkEmail = "email"
+)
+
+// Sink posts a deployment notification somewhere people will see it
+type Sink interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// SinkConfig is a single sink, it's only sent notifications for the listed environments or every environment when
+// none are listed
+type SinkConfig struct {
+	Type         string   `json:"type"`
+	URL          string   `json:"url,omitempty"`
+	Channel      string   `json:"channel,omitempty"`
+	To           []string `json:"to,omitempty"`
+	Environments []string `json:"environments,omitempty"`
+}
+
+// Sinks is decoded by envconfig from a json array of SinkConfig
+type Sinks []SinkConfig
+
+func (s *Sinks) Decode(value string) error {
+	return json.Unmarshal([]byte(value), s)
+}
+
+type Config struct {
+	NotifySinks   Sinks         `envconfig:"NOTIFY_SINKS"`
+	NotifyTimeout time.Duration `envconfig:"NOTIFY_TIMEOUT" default:"5s"`
+	SMTPHost      string        `envconfig:"SMTP_HOST"`
+	SMTPPort      int           `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername  string        `envconfig:"SMTP_USERNAME"`
+	SMTPPassword  string        `envconfig:"SMTP_PASSWORD"`
+	SMTPFrom      string        `envconfig:"SMTP_FROM"`
+}
+
+// ArtifactResult is one service or job in the deployment, FromVersion is empty when it wasn't deployed before
+type ArtifactResult struct {
+	Name        string
+	FromVersion string
+	ToVersion   string
+	Result      eve.DeployArtifactResult
+}
+
+type Notification struct {
+	DeploymentID uuid.UUID
+	Environment  string
+	Namespace    string
+	Type         eve.PlanType
+	State        eve.DeploymentState
+	Artifacts    []ArtifactResult
+	Messages     []string
+}
+
+func (n Notification) Title() string {
+	return fmt.Sprintf("Deployment %s: %s/%s", n.State, n.Environment, n.Namespace)
+}
+
+// Succeeded is used by the sinks to pick a color
+func (n Notification) Succeeded() bool {
+	return n.State == eve.DeploymentStateCompleted
+}
+
+// Lines returns a line per artifact, e.g. "api: 1.2.0 -> 1.3.0 (success)"
+func (n Notification) Lines() []string {
+	var lines []string
+	for _, x := range n.Artifacts {
+		from := x.FromVersion
+		if len(from) == 0 {
+			from = "none"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s -> %s (%s)", x.Name, from, x.ToVersion, x.Result))
+	}
+	return lines
+}
+
+// PlanNotification formats the final plan the scheduler sent back
+func PlanNotification(plan *eve.NSDeploymentPlan) Notification {
+	n := Notification{
+		DeploymentID: plan.DeploymentID,
+		Environment:  plan.EnvironmentName,
+		Type:         plan.Type,
+		State:        plan.State,
+		Messages:     plan.Messages,
+	}
+	if plan.Namespace != nil {
+		n.Namespace = plan.Namespace.Name
+	}
+	for _, x := range plan.Services {
+		n.Artifacts = append(n.Artifacts, artifactResult(x.ServiceName, x.DeployArtifact))
+	}
+	for _, x := range plan.Jobs {
+		n.Artifacts = append(n.Artifacts, artifactResult(x.JobName, x.DeployArtifact))
+	}
+	return n
+}
+
+func artifactResult(name string, a *eve.DeployArtifact) ArtifactResult {
+	if a == nil {
+		return ArtifactResult{Name: name}
+	}
+	return ArtifactResult{
+		Name:        name,
+		FromVersion: a.DeployedVersion,
+		ToVersion:   a.AvailableVersion,
+		Result:      a.Result,
+	}
+}
+
+// CallbackNotification formats a deployment that was finished by eve (cancelled, timed out), there aren't any results
+func CallbackNotification(m eve.DeploymentCallbackMessage, environment string, namespace string) Notification {
+	return Notification{
+		DeploymentID: m.DeploymentID,
+		Environment:  environment,
+		Namespace:    namespace,
+		Type:         m.Type,
+		State:        m.State,
+		Messages:     m.Messages,
+	}
+}
+
+type route struct {
+	sink         Sink
+	environments []string
+}
+
+func (r route) matches(environment string) bool {
+	if len(r.environments) == 0 {
+		return true
+	}
+	for _, x := range r.environments {
+		if strings.EqualFold(x, environment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier sends deployment notifications to the sinks configured for the deployment's environment
+type Notifier struct {
+	routes  []route
+	log     *zap.Logger
+	timeout time.Duration
+	sending sync.WaitGroup
+}
+
+func New(cfg Config) (*Notifier, error) {
+	client := &http.Client{
+		Timeout:   cfg.NotifyTimeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+
+	n := &Notifier{log: log.Logger, timeout: cfg.NotifyTimeout}
+	for _, x := range cfg.NotifySinks {
+		var sink Sink
+		switch x.Type {
+		case SinkSlack:
+			sink = NewSlack(client, x.URL, x.Channel)
+		case SinkTeams:
+			sink = NewTeams(client, x.URL)
+		case SinkEmail:
+			if len(cfg.SMTPHost) == 0 || len(x.To) == 0 {
+				return nil, errors.Wrapf("email notifications require an smtp host and at least one recipient")
+			}
+			sink = NewEmail(cfg, x.To)
+		default:
+			return nil, errors.Wrapf("invalid notification sink type: %s", x.Type)
+		}
+		n.Add(sink, x.Environments...)
+	}
+	return n, nil
+}
+
+// Add registers a sink for the environments, or every environment when none are supplied
+func (n *Notifier) Add(sink Sink, environments ...string) {
+	n.routes = append(n.routes, route{sink: sink, environments: environments})
+}
+
+// Notify sends the notification to every matching sink in the background, so a slow sink never holds up the
+// deployment. Every send gets its own timeout and failures are only logged
+func (n *Notifier) Notify(ctx context.Context, notification Notification) {
+	reqID := log.GetReqID(ctx)
+	for _, x := range n.routes {
+		if !x.matches(notification.Environment) {
+			continue
+		}
+
+		n.sending.Add(1)
+		go func(sink Sink) {
+			defer n.sending.Done()
+			sCtx, cancel := context.WithTimeout(context.WithValue(context.Background(), log.RequestIDKey, reqID), n.timeout)
+			defer cancel()
+			if err := sink.Send(sCtx, notification); err != nil {
+				n.log.Warn("deployment notification failed",
+					zap.String("req_id", reqID),
+					zap.String("id", notification.DeploymentID.String()),
+					zap.String("sink", fmt.Sprintf("%T", sink)),
+					zap.Error(err),
+				)
+			}
+		}(x.sink)
+	}
+}
+
+// Wait blocks until the notifications that are being sent are done
+func (n *Notifier) Wait() {
+	n.sending.Wait()
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Wrapf("notification returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func testPlan() *eve.NSDeploymentPlan {
+	return &eve.NSDeploymentPlan{
+		EnvironmentName: "prod",
+		Namespace:       &eve.NamespaceRequest{Name: "prod-app"},
+		State:           eve.DeploymentStatePartial,
+		Messages:        []string{"api failed to start"},
+		Services: eve.DeployServices{
+			{ServiceName: "api", DeployArtifact: &eve.DeployArtifact{DeployedVersion: "1.2.0", AvailableVersion: "1.3.0", Result: eve.DeployArtifactResultFailed}},
+			{ServiceName: "web", DeployArtifact: &eve.DeployArtifact{AvailableVersion: "2.0.0", Result: eve.DeployArtifactResultSuccess}},
+		},
+	}
+}
+
+func TestPlanNotification_Lines(t *testing.T) {
+	n := PlanNotification(testPlan())
+
+	expected := []string{
+		"api: 1.2.0 -> 1.3.0 (failed)",
+		"web: none -> 2.0.0 (success)",
+	}
+	lines := n.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("expected: %v, got: %v", expected, lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("expected: %s, got: %s", expected[i], lines[i])
+		}
+	}
+
+	if n.Title() != "Deployment partial: prod/prod-app" {
+		t.Errorf("unexpected title: %s", n.Title())
+	}
+}
+
+func TestNotifier_RoutesByEnvironment(t *testing.T) {
+	var received []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Errorf("failed to decode the slack message: %s", err)
+		}
+		received = append(received, m)
+	}))
+	defer server.Close()
+
+	n, err := New(Config{NotifyTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Add(NewSlack(server.Client(), server.URL, "#releases"), "prod")
+
+	n.Notify(context.Background(), PlanNotification(testPlan()))
+	n.Notify(context.Background(), Notification{Environment: "qa", State: eve.DeploymentStateCompleted})
+	n.Wait()
+
+	if len(received) != 1 {
+		t.Fatalf("expected 1 slack message, got: %d", len(received))
+	}
+	if received[0].Channel != "#releases" || len(received[0].Attachments) != 1 || received[0].Attachments[0].Color != "danger" {
+		t.Errorf("unexpected slack message: %+v", received[0])
+	}
+	if !strings.Contains(received[0].Attachments[0].Text, "api: 1.2.0 -> 1.3.0 (failed)") {
+		t.Errorf("expected the service results in the message, got: %s", received[0].Attachments[0].Text)
+	}
+}
+
+func TestEmail_Send(t *testing.T) {
+	var msg string
+	e := NewEmail(Config{SMTPHost: "localhost", SMTPPort: 25, SMTPFrom: "eve@example.com"}, []string{"ops@example.com"})
+	e.sendMail = func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, m []byte) error {
+		if addr != "localhost:25" || from != "eve@example.com" || len(to) != 1 {
+			t.Errorf("unexpected mail: %s %s %v", addr, from, to)
+		}
+		msg = string(m)
+		return nil
+	}
+
+	if err := e.Send(context.Background(), PlanNotification(testPlan())); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg, "Subject: Deployment partial: prod/prod-app\r\n") || !strings.Contains(msg, "api failed to start") {
+		t.Errorf("unexpected mail body: %s", msg)
+	}
+}
+
+type blockingSink struct {
+	release chan struct{}
+	sent    chan struct{}
+}
+
+func (s blockingSink) Send(ctx context.Context, n Notification) error {
+	<-s.release
+	close(s.sent)
+	return nil
+}
+
+func TestNotifier_DoesNotBlock(t *testing.T) {
+	n, err := New(Config{NotifyTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := blockingSink{release: make(chan struct{}), sent: make(chan struct{})}
+	n.Add(sink)
+
+	n.Notify(context.Background(), PlanNotification(testPlan()))
+	close(sink.release)
+	n.Wait()
+
+	select {
+	case <-sink.sent:
+	default:
+		t.Error("expected the notification to be sent once Wait returned")
+	}
+}
+
+func TestEmail_SendTimesOut(t *testing.T) {
+	// the server accepts the connection but never greets, like an smtp server that's hung
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	e := NewEmail(Config{SMTPHost: host, SMTPPort: p, SMTPFrom: "eve@example.com", NotifyTimeout: time.Second}, []string{"ops@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	now := time.Now()
+	if err = e.Send(ctx, PlanNotification(testPlan())); err == nil {
+		t.Fatal("expected the send to time out")
+	}
+	if time.Since(now) > 500*time.Millisecond {
+		t.Errorf("expected the send to give up at the context deadline, took %s", time.Since(now))
+	}
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color string `json:"color"`
+	Text  string `json:"text"`
+}
+
+// Slack posts to an incoming webhook, the channel overrides the webhook's default channel when it's set
+type Slack struct {
+	client  *http.Client
+	url     string
+	channel string
+}
+
+func NewSlack(client *http.Client, url string, channel string) *Slack {
+	return &Slack{client: client, url: url, channel: channel}
+}
+
+func (s *Slack) Send(ctx context.Context, n Notification) error {
+	var b strings.Builder
+	for _, x := range n.Lines() {
+		b.WriteString("• " + x + "\n")
+	}
+	for _, x := range n.Messages {
+		b.WriteString("> " + x + "\n")
+	}
+
+	color := "danger"
+	if n.Succeeded() {
+		color = "good"
+	}
+
+	return postJSON(ctx, s.client, s.url, slackMessage{
+		Channel: s.channel,
+		Text:    "*" + n.Title() + "*",
+		Attachments: []slackAttachment{{
+			Color: color,
+			Text:  strings.TrimSpace(b.String()),
+		}},
+	})
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type teamsMessageCard struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	Summary    string `json:"summary"`
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+	ThemeColor string `json:"themeColor"`
+}
+
+// Teams posts a message card to an incoming webhook connector
+type Teams struct {
+	client *http.Client
+	url    string
+}
+
+func NewTeams(client *http.Client, url string) *Teams {
+	return &Teams{client: client, url: url}
+}
+
+func (t *Teams) Send(ctx context.Context, n Notification) error {
+	var lines []string
+	for _, x := range n.Lines() {
+		lines = append(lines, "- "+x)
+	}
+	for _, x := range n.Messages {
+		lines = append(lines, "> "+x)
+	}
+
+	color := "D70000"
+	if n.Succeeded() {
+		color = "2EB886"
+	}
+
+	return postJSON(ctx, t.client, t.url, teamsMessageCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		Summary:    n.Title(),
+		Title:      n.Title(),
+		Text:       strings.Join(lines, "\n\n"),
+		ThemeColor: color,
+	})
+}
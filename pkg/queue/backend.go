@@ -0,0 +1,25 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	BackendSQS      = "sqs"
+	BackendPostgres = "postgres"
+)
+
+// Backend is the message transport used by the Worker, the SQS FIFO queue (Q) is the default
+// and PostgresQ can be used when eve runs somewhere without AWS
+type Backend interface {
+	Message(ctx context.Context, m *M) error
+	// Receive returns the messages with the context they were sent with attached (M.WithContext)
+	Receive(ctx context.Context) ([]*M, error)
+	Delete(ctx context.Context, m *M) error
+	// ChangeVisibility hides a received message for timeout from now, a timeout of 0 makes it visible right away
+	ChangeVisibility(ctx context.Context, m *M, timeout time.Duration) error
+	URL() string
+	// Queue returns a Backend of the same type for a different queue url/name
+	Queue(qUrl string) Backend
+}
@@ -12,4 +12,6 @@ const (
 	CommandDeployNamespace string = "sch-deploy-namespace"
 	// CommandRestartNamespace is the command used for scheduling a service restart in a namespace
 	CommandRestartNamespace string = "sch-restart-namespace"
+	// CommandCancelNamespace is the command used for cancelling a deployment the scheduler is still working on
+	CommandCancelNamespace string = "sch-cancel-namespace"
 )
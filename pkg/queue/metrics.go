@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	statWorkerInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eve_queue_worker_in_flight",
+			Help: "Number of queue messages currently being handled",
+		}, []string{"worker", "command"})
+
+	statMessagesReceived = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eve_queue_messages_received_total",
+			Help: "The total number of queue messages received",
+		}, []string{"worker", "command"})
+
+	statMessagesHandled = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eve_queue_messages_handled_total",
+			Help: "The total number of queue messages handled by result",
+		}, []string{"worker", "command", "result"})
+
+	statMessagesDeadLettered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eve_queue_messages_dead_lettered_total",
+			Help: "The total number of queue messages removed after running out of attempts",
+		}, []string{"worker", "command"})
+
+	statMessageDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "eve_queue_message_duration_seconds",
+			Help: "Time it takes to handle a queue message",
+		}, []string{"worker", "command"})
+)
@@ -0,0 +1,278 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+
+	"github.com/unanet/go/pkg/errors"
+	"github.com/unanet/go/pkg/json"
+	"github.com/unanet/go/pkg/log"
+)
+
+const (
+	// dedupeWindow matches the SQS FIFO deduplication interval
+	dedupeWindow = 5 * time.Minute
+	pollInterval = time.Second
+)
+
+// PostgresQ is a FIFO queue backed by the queue_message table. Like SQS FIFO only the oldest message in a group
+// can be received, and the rest of the group waits until it's been deleted or its visibility timeout expires
+type PostgresQ struct {
+	db  *sqlx.DB
+	c   Config
+	log *zap.Logger
+	id  uint64
+}
+
+func NewPostgresQ(db *sqlx.DB, config Config) *PostgresQ {
+	qID := atomic.AddUint64(&queueID, 1)
+	return &PostgresQ{
+		id:  qID,
+		c:   config,
+		db:  db,
+		log: log.Logger.With(zap.String("queue_url", config.QueueURL), zap.Uint64("internal_queue_id", qID)),
+	}
+}
+
+func (q *PostgresQ) logWith(ctx context.Context) *zap.Logger {
+	return q.log.With(zap.String("req_id", log.GetReqID(ctx)))
+}
+
+func (q *PostgresQ) URL() string {
+	return q.c.QueueURL
+}
+
+func (q *PostgresQ) Queue(qUrl string) Backend {
+	return NewPostgresQ(q.db, Config{
+		QueueURL: qUrl,
+	})
+}
+
+func (q *PostgresQ) Message(ctx context.Context, m *M) (err error) {
+	if len(m.Command) == 0 {
+		m.Command = "empty"
+	}
+	ctx, span := startSendSpan(ctx, q.c.QueueURL, m)
+	defer func() { endSpan(span, err) }()
+
+	body := m.ID.String()
+	if len(m.Body) > 0 {
+		body = m.Body.String()
+	}
+
+	dedupeID := sql.NullString{
+		String: m.DedupeID,
+		Valid:  len(m.DedupeID) > 0,
+	}
+
+	traceContext, err := json.StructToJsonObject(InjectTraceContext(ctx))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	now := time.Now()
+	var messageID uuid.UUID
+	err = q.db.QueryRowxContext(ctx, `
+		insert into queue_message(queue, group_id, dedupe_id, command, req_id, eve_id, body, trace_context)
+		select $1, $2, $3, $4, $5, $6, $7, $9
+		where $3::text is null or not exists (
+			select 1 from queue_message
+			where queue = $1 and dedupe_id = $3 and created_at > now() - make_interval(secs => $8)
+		)
+		on conflict (queue, dedupe_id) where dedupe_id is not null and deleted_at is null do nothing
+		returning message_id
+	`, q.c.QueueURL, m.GroupID, dedupeID, m.Command, log.GetReqID(ctx), m.ID, body, dedupeWindow.Seconds(), traceContext).
+		Scan(&messageID)
+	if err != nil {
+		if !goErrors.Is(err, sql.ErrNoRows) {
+			return errors.Wrap(err)
+		}
+		// the message was a duplicate, so just like SQS we hand back the id of the original. A message that's still on
+		// the queue is a duplicate even after the dedupe window, the unique index is what stops concurrent sends
+		err = q.db.QueryRowxContext(ctx, `
+			select message_id from queue_message
+			where queue = $1 and dedupe_id = $2
+			order by id desc
+			limit 1
+		`, q.c.QueueURL, dedupeID).Scan(&messageID)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+	}
+
+	m.MessageID = messageID.String()
+	q.logWith(ctx).Info("postgres queue message sent",
+		zap.Float64("elapsed_ms", float64(time.Since(now).Nanoseconds())/1000000.0),
+		zap.Any("id", m.ID),
+		zap.String("message_id", m.MessageID),
+	)
+	return nil
+}
+
+type postgresM struct {
+	MessageID     uuid.UUID      `db:"message_id"`
+	GroupID       string         `db:"group_id"`
+	DedupeID      sql.NullString `db:"dedupe_id"`
+	Command       string         `db:"command"`
+	ReqID         sql.NullString `db:"req_id"`
+	EveID         uuid.NullUUID  `db:"eve_id"`
+	Body          string         `db:"body"`
+	ReceiptHandle uuid.UUID      `db:"receipt_handle"`
+	ReceiveCount  int            `db:"receive_count"`
+	TraceContext  json.Object    `db:"trace_context"`
+}
+
+// Receive polls for up to WaitTimeSecond, the same as an SQS long poll
+func (q *PostgresQ) Receive(ctx context.Context) ([]*M, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	waitExceeded := time.After(time.Duration(q.c.WaitTimeSecond) * time.Second)
+
+	for {
+		ms, err := q.receive(ctx)
+		if err != nil {
+			if goErrors.Is(err, context.Canceled) {
+				return nil, nil
+			}
+			return nil, errors.Wrap(err)
+		}
+		if len(ms) > 0 {
+			return ms, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-waitExceeded:
+			return nil, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *PostgresQ) receive(ctx context.Context) ([]*M, error) {
+	// deleted messages are kept around for the dedupe window
+	_, err := q.db.ExecContext(ctx, `
+		delete from queue_message
+		where queue = $1 and deleted_at < now() - make_interval(secs => $2)
+	`, q.c.QueueURL, dedupeWindow.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	// a message is only a candidate when it's the oldest remaining message in its group, that way an in flight
+	// message blocks the rest of its group until it's deleted or becomes visible again
+	rows, err := q.db.QueryxContext(ctx, `
+		update queue_message
+		set receipt_handle = uuid_generate_v4(),
+		    visible_at = now() + make_interval(secs => $3),
+		    receive_count = receive_count + 1
+		where id in (
+			select m.id
+			from queue_message m
+			where m.queue = $1
+			  and m.deleted_at is null
+			  and m.visible_at <= now()
+			  and not exists (
+				select 1 from queue_message o
+				where o.queue = m.queue and o.group_id = m.group_id and o.deleted_at is null and o.id < m.id
+			  )
+			order by m.id
+			limit $2
+			for update skip locked
+		)
+		returning message_id, group_id, dedupe_id, command, req_id, eve_id, body, receipt_handle, receive_count, trace_context
+	`, q.c.QueueURL, q.c.MaxNumberOfMessage, q.c.VisibilityTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returnMs []*M
+	for rows.Next() {
+		var x postgresM
+		err = rows.StructScan(&x)
+		if err != nil {
+			return nil, err
+		}
+
+		m := M{
+			ID:            x.EveID.UUID,
+			GroupID:       x.GroupID,
+			Command:       x.Command,
+			Body:          json.Object(x.Body),
+			ReceiptHandle: x.ReceiptHandle.String(),
+			MessageID:     x.MessageID.String(),
+			DedupeID:      x.DedupeID.String,
+			ReceiveCount:  x.ReceiveCount,
+		}
+		mctx := context.WithValue(ctx, log.RequestIDKey, x.ReqID.String)
+		carrier := TraceContext{}
+		_ = x.TraceContext.Unmarshal(&carrier)
+		mctx = ExtractTraceContext(mctx, carrier)
+		returnMs = append(returnMs, m.WithContext(mctx))
+		q.logWith(mctx).Info("postgres queue message received",
+			zap.Any("id", m.ID),
+			zap.String("message_id", m.MessageID),
+		)
+	}
+
+	return returnMs, rows.Err()
+}
+
+// Delete removes the message, a stale receipt handle (the visibility timeout expired and the message was received
+// again) is ignored the same way SQS ignores it
+func (q *PostgresQ) Delete(ctx context.Context, m *M) error {
+	receiptHandle, err := uuid.FromString(m.ReceiptHandle)
+	if err != nil {
+		return errors.Wrapf("invalid receipt handle: %s", m.ReceiptHandle)
+	}
+
+	now := time.Now()
+	_, err = q.db.ExecContext(ctx, `
+		update queue_message
+		set deleted_at = now(),
+		    receipt_handle = null
+		where queue = $1 and receipt_handle = $2 and deleted_at is null
+	`, q.c.QueueURL, receiptHandle)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	q.logWith(ctx).Info("postgres queue message deleted",
+		zap.Float64("elapsed_ms", float64(time.Since(now).Nanoseconds())/1000000.0),
+		zap.Any("id", m.ID),
+		zap.String("message_id", m.MessageID),
+	)
+	return nil
+}
+
+func (q *PostgresQ) ChangeVisibility(ctx context.Context, m *M, timeout time.Duration) error {
+	receiptHandle, err := uuid.FromString(m.ReceiptHandle)
+	if err != nil {
+		return errors.Wrapf("invalid receipt handle: %s", m.ReceiptHandle)
+	}
+
+	_, err = q.db.ExecContext(ctx, `
+		update queue_message
+		set visible_at = now() + make_interval(secs => $3)
+		where queue = $1 and receipt_handle = $2 and deleted_at is null
+	`, q.c.QueueURL, receiptHandle, timeout.Seconds())
+	if err != nil {
+		return errors.Wrap(err)
+	}
+
+	q.logWith(ctx).Info("postgres queue message visibility changed",
+		zap.Any("id", m.ID),
+		zap.String("message_id", m.MessageID),
+		zap.Duration("timeout", timeout),
+	)
+	return nil
+}
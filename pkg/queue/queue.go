@@ -3,6 +3,7 @@ package queue
 import (
 	"context"
 	goerrors "github.com/pkg/errors"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	uuid "github.com/satori/go.uuid"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 
 	"github.com/unanet/go/pkg/errors"
@@ -64,21 +66,37 @@ type M struct {
 	MessageID     string
 	Command       string
 	DedupeID      string
+	ReceiveCount  int
+	ctx           context.Context
 }
 
-type mContext struct {
-	M
-	ctx context.Context
+// Context returns the context a received message was sent with, it carries the request id and the trace
+func (m *M) Context() context.Context {
+	if m.ctx != nil {
+		return m.ctx
+	}
+	return context.Background()
+}
+
+// WithContext returns a copy of the message with its context set, a Backend uses it to attach the request id and
+// trace a message was sent with when it's received
+func (m *M) WithContext(ctx context.Context) *M {
+	c := *m
+	c.ctx = ctx
+	return &c
 }
 
 func (q *Q) logWith(ctx context.Context) *zap.Logger {
 	return q.log.With(zap.String("req_id", log.GetReqID(ctx)))
 }
 
-func (q *Q) Message(ctx context.Context, m *M) error {
+func (q *Q) Message(ctx context.Context, m *M) (err error) {
 	if len(m.Command) == 0 {
 		m.Command = "empty"
 	}
+	ctx, span := startSendSpan(ctx, q.c.QueueURL, m)
+	defer func() { endSpan(span, err) }()
+
 	awsM := sqs.SendMessageInput{
 		MessageAttributes: map[string]*sqs.MessageAttributeValue{
 			MessageAttributeReqID: {
@@ -102,6 +120,13 @@ func (q *Q) Message(ctx context.Context, m *M) error {
 		awsM.MessageDeduplicationId = aws.String(m.DedupeID)
 	}
 
+	for k, v := range InjectTraceContext(ctx) {
+		awsM.MessageAttributes[k] = &sqs.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+
 	q.logWith(ctx).Info("preparing to send message to queue", zap.String("queue", *awsM.QueueUrl))
 
 	if len(m.Body) > 0 {
@@ -128,10 +153,11 @@ func (q *Q) Message(ctx context.Context, m *M) error {
 	return nil
 }
 
-func (q *Q) Receive(ctx context.Context) ([]*mContext, error) {
+func (q *Q) Receive(ctx context.Context) ([]*M, error) {
 	awsM := sqs.ReceiveMessageInput{
 		AttributeNames: []*string{
 			aws.String(sqs.MessageSystemAttributeNameMessageGroupId),
+			aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
 		},
 		MessageAttributeNames: []*string{
 			aws.String(sqs.QueueAttributeNameAll),
@@ -149,9 +175,10 @@ func (q *Q) Receive(ctx context.Context) ([]*mContext, error) {
 		return nil, errors.Wrap(err)
 	}
 
-	var returnMs []*mContext
+	var returnMs []*M
 	for _, x := range result.Messages {
 		id := uuid.FromStringOrNil(*x.MessageAttributes[MessageAttributeID].StringValue)
+		receiveCount, _ := strconv.Atoi(aws.StringValue(x.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]))
 		m := M{
 			ID:            id,
 			GroupID:       *x.Attributes[sqs.MessageSystemAttributeNameMessageGroupId],
@@ -159,12 +186,17 @@ func (q *Q) Receive(ctx context.Context) ([]*mContext, error) {
 			Body:          json.Object(*x.Body),
 			ReceiptHandle: *x.ReceiptHandle,
 			MessageID:     *x.MessageId,
+			ReceiveCount:  receiveCount,
 		}
 		mctx := context.WithValue(ctx, log.RequestIDKey, *x.MessageAttributes[MessageAttributeReqID].StringValue)
-		returnMs = append(returnMs, &mContext{
-			M:   m,
-			ctx: mctx,
-		})
+		carrier := TraceContext{}
+		for _, field := range otel.GetTextMapPropagator().Fields() {
+			if attr, ok := x.MessageAttributes[field]; ok {
+				carrier[field] = aws.StringValue(attr.StringValue)
+			}
+		}
+		mctx = ExtractTraceContext(mctx, carrier)
+		returnMs = append(returnMs, m.WithContext(mctx))
 		q.logWith(mctx).Info("AWS SQS message received",
 			zap.Any("id", m.ID),
 			zap.String("message_id", m.MessageID),
@@ -174,6 +206,16 @@ func (q *Q) Receive(ctx context.Context) ([]*mContext, error) {
 	return returnMs, nil
 }
 
+func (q *Q) URL() string {
+	return q.c.QueueURL
+}
+
+func (q *Q) Queue(qUrl string) Backend {
+	return NewQ(q.sess, Config{
+		QueueURL: qUrl,
+	})
+}
+
 func (q *Q) Delete(ctx context.Context, m *M) error {
 	now := time.Now()
 	_, err := q.aws.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
@@ -190,3 +232,20 @@ func (q *Q) Delete(ctx context.Context, m *M) error {
 	)
 	return nil
 }
+
+func (q *Q) ChangeVisibility(ctx context.Context, m *M, timeout time.Duration) error {
+	_, err := q.aws.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(q.c.QueueURL),
+		ReceiptHandle:     aws.String(m.ReceiptHandle),
+		VisibilityTimeout: aws.Int64(int64(timeout.Seconds())),
+	})
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	q.logWith(ctx).Info("AWS SQS message visibility changed",
+		zap.Any("id", m.ID),
+		zap.String("message_id", m.MessageID),
+		zap.Duration("timeout", timeout),
+	)
+	return nil
+}
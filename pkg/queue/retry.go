@@ -0,0 +1,34 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy controls what happens to a message when the Handler returns an error. The message is made visible again
+// after an exponential backoff, and once it's been received MaxAttempts times it's handed to the DeadLetterSink and deleted
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Backoff returns how long a message that has been received receiveCount times stays invisible
+func (p RetryPolicy) Backoff(receiveCount int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < receiveCount && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+func (p RetryPolicy) Exhausted(receiveCount int) bool {
+	return p.MaxAttempts > 0 && receiveCount >= p.MaxAttempts
+}
+
+type DeadLetterSink interface {
+	DeadLetter(ctx context.Context, queueURL string, m *M, cause error) error
+}
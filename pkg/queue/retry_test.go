@@ -0,0 +1,30 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    2 * time.Minute,
+	}
+
+	expected := map[int]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		4: 2 * time.Minute,
+	}
+	for receiveCount, delay := range expected {
+		if actual := policy.Backoff(receiveCount); actual != delay {
+			t.Errorf("receive count: %d, expected: %s, got: %s", receiveCount, delay, actual)
+		}
+	}
+
+	if policy.Exhausted(4) || !policy.Exhausted(5) {
+		t.Error("expected the policy to be exhausted on the 5th attempt")
+	}
+}
@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/unanet/eve/pkg/queue")
+
+// TraceContext carries the trace across a queue hop, as message attributes or as part of a message body
+type TraceContext map[string]string
+
+func (c TraceContext) Get(key string) string {
+	return c[key]
+}
+
+func (c TraceContext) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c TraceContext) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// InjectTraceContext returns the trace context of ctx so it can travel with a message
+func InjectTraceContext(ctx context.Context) TraceContext {
+	carrier := TraceContext{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// ExtractTraceContext continues the trace a message was sent with
+func ExtractTraceContext(ctx context.Context, carrier TraceContext) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+func startSendSpan(ctx context.Context, queueURL string, m *M) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "queue.send "+m.Command,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination", queueURL),
+			attribute.String("messaging.message_group_id", m.GroupID),
+			attribute.String("eve.id", m.ID.String()),
+		))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -5,7 +5,8 @@ import (
 	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws/session"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/unanet/go/pkg/log"
@@ -24,35 +25,80 @@ type Handler interface {
 }
 
 type Worker struct {
-	q       *Q
-	log     *zap.Logger
-	name    string
-	timeout time.Duration
-	ctx     context.Context
-	cancel  context.CancelFunc
-	done    chan bool
-	wqs     map[string]*Q
-	mutex   sync.Mutex
-	sess    *session.Session
-}
-
-func NewWorker(name string, q *Q, timeout time.Duration) *Worker {
+	q             Backend
+	log           *zap.Logger
+	name          string
+	timeout       time.Duration
+	ctx           context.Context
+	cancel        context.CancelFunc
+	handlerCtx    context.Context
+	handlerCancel context.CancelFunc
+	done          chan bool
+	wqs           map[string]Backend
+	mutex         sync.Mutex
+	retry         RetryPolicy
+	dlq           DeadLetterSink
+	slots         chan struct{}
+	commandSlots  map[string]chan struct{}
+	inFlight      sync.WaitGroup
+	visibility    time.Duration
+}
+
+const (
+	// receiveErrorDelay is how long the worker waits before trying to receive again when the queue returns an error
+	receiveErrorDelay  = 5 * time.Second
+	defaultConcurrency = 10
+)
+
+func NewWorker(name string, q Backend, timeout time.Duration) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
+	handlerCtx, handlerCancel := context.WithCancel(context.Background())
 	w := Worker{
-		name:    name,
-		q:       q,
-		log:     log.Logger.With(zap.Uint64("internal_queue_id", q.id), zap.String("worker", name)),
-		timeout: timeout,
-		ctx:     ctx,
-		cancel:  cancel,
-		sess:    q.sess,
-		done:    make(chan bool),
-		wqs:     make(map[string]*Q),
+		name:          name,
+		q:             q,
+		log:           log.Logger.With(zap.String("worker", name)),
+		timeout:       timeout,
+		ctx:           ctx,
+		cancel:        cancel,
+		handlerCtx:    handlerCtx,
+		handlerCancel: handlerCancel,
+		done:          make(chan bool),
+		wqs:           make(map[string]Backend),
+		slots:         make(chan struct{}, defaultConcurrency),
+		commandSlots:  make(map[string]chan struct{}),
 	}
 
 	return &w
 }
 
+// SetRetryPolicy makes failed messages visible again after a backoff, messages that run out of attempts
+// are handed to the sink and deleted. Without a policy failed messages are only logged
+func (worker *Worker) SetRetryPolicy(policy RetryPolicy, sink DeadLetterSink) {
+	worker.retry = policy
+	worker.dlq = sink
+}
+
+// SetVisibilityHeartbeat keeps extending the visibility of a message by timeout for as long as its handler is
+// running, so a slow handler doesn't have its message delivered a second time
+func (worker *Worker) SetVisibilityHeartbeat(timeout time.Duration) {
+	worker.visibility = timeout
+}
+
+// SetConcurrency limits how many messages are handled at once, and optionally how many of those can be a given command.
+// A message waiting on its command limit still holds a slot in the pool. It needs to be called before Start
+func (worker *Worker) SetConcurrency(limit int, commandLimits map[string]int) {
+	if limit > 0 {
+		worker.slots = make(chan struct{}, limit)
+	}
+	worker.commandSlots = make(map[string]chan struct{})
+	for command, commandLimit := range commandLimits {
+		if commandLimit > 0 {
+			worker.commandSlots[command] = make(chan struct{}, commandLimit)
+		}
+	}
+}
+
+// Start keeps receiving messages while handlers are running, as long as there's a free slot in the pool
 func (worker *Worker) Start(h Handler) {
 	worker.log.Info("Queue worker started")
 	for {
@@ -62,10 +108,14 @@ func (worker *Worker) Start(h Handler) {
 			close(worker.done)
 			return
 		default:
-			ctx := context.Background()
-			m, err := worker.q.Receive(ctx)
+			m, err := worker.q.Receive(worker.ctx)
 			if err != nil {
-				worker.log.Panic("error receiving message from queue", zap.Error(err))
+				worker.log.Error("error receiving message from queue", zap.Error(err))
+				select {
+				case <-worker.ctx.Done():
+				case <-time.After(receiveErrorDelay):
+				}
+				continue
 			}
 			if len(m) == 0 {
 				continue
@@ -75,24 +125,38 @@ func (worker *Worker) Start(h Handler) {
 	}
 }
 
+// Stop stops receiving messages and waits for the handlers that are running to finish. Handlers that are still
+// running after the worker timeout have their context cancelled
 func (worker *Worker) Stop() {
 	worker.cancel()
 	<-worker.done
+
+	drained := make(chan struct{})
+	go func() {
+		worker.inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		worker.log.Info("queue worker drained")
+	case <-time.After(worker.timeout):
+		worker.log.Warn("queue worker drain deadline exceeded, cancelling in flight messages")
+	}
+	worker.handlerCancel()
 }
 
 func (worker *Worker) DeleteMessage(ctx context.Context, m *M) error {
 	return worker.q.Delete(ctx, m)
 }
 
-func (worker *Worker) getQueue(qUrl string) *Q {
+func (worker *Worker) getQueue(qUrl string) Backend {
 	worker.mutex.Lock()
 	defer worker.mutex.Unlock()
 	if val, ok := worker.wqs[qUrl]; ok {
 		return val
 	}
-	q := NewQ(worker.sess, Config{
-		QueueURL: qUrl,
-	})
+	q := worker.q.Queue(qUrl)
 	worker.wqs[qUrl] = q
 	return q
 }
@@ -102,21 +166,151 @@ func (worker *Worker) Message(ctx context.Context, qUrl string, m *M) error {
 	return q.Message(ctx, m)
 }
 
-func (worker *Worker) run(h Handler, mCtx []*mContext) {
-	numMessages := len(mCtx)
-	var wg sync.WaitGroup
-	wg.Add(numMessages)
-	for _, mc := range mCtx {
-		go func(m *mContext) {
-			ctx, cancel := context.WithTimeout(m.ctx, worker.timeout)
+func (worker *Worker) run(h Handler, ms []*M) {
+	for i, m := range ms {
+		statMessagesReceived.WithLabelValues(worker.name, m.Command).Inc()
+		select {
+		case worker.slots <- struct{}{}:
+		case <-worker.ctx.Done():
+			worker.release(ms[i:])
+			return
+		}
+
+		worker.inFlight.Add(1)
+		go func(m *M) {
+			defer worker.inFlight.Done()
+			defer func() { <-worker.slots }()
+
+			// the handler context is only cancelled when the worker is stopped and the drain deadline has passed,
+			// it carries the request id and the trace the message was sent with
+			hCtx := context.WithValue(worker.handlerCtx, log.RequestIDKey, log.GetReqID(m.Context()))
+			hCtx = trace.ContextWithRemoteSpanContext(hCtx, trace.SpanContextFromContext(m.Context()))
+			if commandSlots, ok := worker.commandSlots[m.Command]; ok {
+				select {
+				case commandSlots <- struct{}{}:
+					defer func() { <-commandSlots }()
+				case <-hCtx.Done():
+					return
+				}
+			}
+
+			statWorkerInFlight.WithLabelValues(worker.name, m.Command).Inc()
+			defer statWorkerInFlight.WithLabelValues(worker.name, m.Command).Dec()
+
+			ctx, cancel := context.WithTimeout(hCtx, worker.timeout)
 			defer cancel()
-			defer wg.Done()
-			if err := h.HandleMessage(ctx, &m.M); err != nil {
+			ctx, span := tracer.Start(ctx, "queue.handle "+m.Command,
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					attribute.String("messaging.destination", worker.q.URL()),
+					attribute.String("messaging.message_id", m.MessageID),
+					attribute.String("eve.id", m.ID.String()),
+				))
+			stopHeartbeat := worker.heartbeat(hCtx, m)
+			now := time.Now()
+			err := h.HandleMessage(ctx, m)
+			statMessageDuration.WithLabelValues(worker.name, m.Command).Observe(time.Since(now).Seconds())
+			stopHeartbeat()
+			endSpan(span, err)
+			if err != nil {
+				statMessagesHandled.WithLabelValues(worker.name, m.Command, "failed").Inc()
 				worker.log.Error("error handling message", zap.Error(err))
+				worker.failed(hCtx, m, err)
+				return
 			}
-		}(mc)
+			statMessagesHandled.WithLabelValues(worker.name, m.Command, "success").Inc()
+		}(m)
+	}
+}
+
+// heartbeatInterval is half of the visibility timeout or the handler timeout, whichever is shorter. A handler
+// never runs longer than its timeout, so an interval derived from a longer visibility would never fire
+func (worker *Worker) heartbeatInterval() time.Duration {
+	interval := worker.visibility
+	if worker.timeout > 0 && worker.timeout < interval {
+		interval = worker.timeout
+	}
+	return interval / 2
+}
+
+// heartbeat extends the visibility of the message every heartbeat interval until the returned func is called
+func (worker *Worker) heartbeat(ctx context.Context, m *M) func() {
+	if worker.visibility <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(worker.heartbeatInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// the handler may have already deleted the message, which is why this is only a debug log
+				if err := worker.q.ChangeVisibility(ctx, m, worker.visibility); err != nil {
+					worker.log.Debug("error extending message visibility", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// release makes messages that were received but never handled visible again so they aren't stuck until
+// their visibility timeout expires
+func (worker *Worker) release(ms []*M) {
+	ctx, cancel := context.WithTimeout(worker.handlerCtx, worker.timeout)
+	defer cancel()
+	for _, m := range ms {
+		if err := worker.q.ChangeVisibility(ctx, m, 0); err != nil {
+			worker.log.Error("error releasing message", zap.Error(err))
+		}
+	}
+}
+
+func (worker *Worker) failed(ctx context.Context, m *M, cause error) {
+	if worker.retry.MaxAttempts == 0 {
+		return
+	}
+
+	// the handler may have used up its own timeout so these get a fresh one
+	ctx, cancel := context.WithTimeout(ctx, worker.timeout)
+	defer cancel()
+
+	if !worker.retry.Exhausted(m.ReceiveCount) {
+		if err := worker.q.ChangeVisibility(ctx, m, worker.retry.Backoff(m.ReceiveCount)); err != nil {
+			worker.log.Error("error changing message visibility", zap.Error(err))
+		}
+		return
+	}
+
+	if worker.dlq != nil {
+		if err := worker.dlq.DeadLetter(ctx, worker.q.URL(), m, cause); err != nil {
+			// leave the message on the queue, it will be retried and dead lettered again
+			worker.log.Error("error dead lettering message", zap.Error(err))
+			return
+		}
+	}
+
+	statMessagesDeadLettered.WithLabelValues(worker.name, m.Command).Inc()
+	worker.log.Warn("message exceeded max attempts and was removed from the queue",
+		zap.Any("id", m.ID),
+		zap.String("message_id", m.MessageID),
+		zap.Int("receive_count", m.ReceiveCount),
+	)
+	if err := worker.q.Delete(ctx, m); err != nil {
+		worker.log.Error("error deleting dead lettered message", zap.Error(err))
 	}
-	wg.Wait()
 }
 
 func GetLogger(ctx context.Context) *zap.Logger {
@@ -0,0 +1,154 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memoryQ struct {
+	mutex       sync.Mutex
+	messages    []*M
+	visibilityN int32
+}
+
+func (q *memoryQ) Message(ctx context.Context, m *M) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.messages = append(q.messages, m.WithContext(ctx))
+	return nil
+}
+
+func (q *memoryQ) Receive(ctx context.Context) ([]*M, error) {
+	q.mutex.Lock()
+	ms := q.messages
+	q.messages = nil
+	q.mutex.Unlock()
+	if len(ms) == 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return ms, nil
+}
+
+func (q *memoryQ) Delete(ctx context.Context, m *M) error {
+	return nil
+}
+
+func (q *memoryQ) ChangeVisibility(ctx context.Context, m *M, timeout time.Duration) error {
+	atomic.AddInt32(&q.visibilityN, 1)
+	return nil
+}
+
+func (q *memoryQ) URL() string {
+	return "memory"
+}
+
+func (q *memoryQ) Queue(qUrl string) Backend {
+	return q
+}
+
+func TestWorker_CommandConcurrency(t *testing.T) {
+	q := &memoryQ{}
+	for i := 0; i < 6; i++ {
+		_ = q.Message(context.TODO(), &M{Command: "slow"})
+	}
+
+	worker := NewWorker("test", q, time.Second)
+	worker.SetConcurrency(4, map[string]int{"slow": 2})
+
+	var running, maxRunning, handled int32
+	go worker.Start(HandlerFunc(func(ctx context.Context, msg *M) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}))
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&handled) < 6 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	worker.Stop()
+
+	if handled != 6 {
+		t.Errorf("expected 6 messages to be handled, got %d", handled)
+	}
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 slow messages at once, got %d", maxRunning)
+	}
+}
+
+func TestWorker_StopDrainsInFlight(t *testing.T) {
+	q := &memoryQ{}
+	_ = q.Message(context.TODO(), &M{Command: "slow"})
+
+	worker := NewWorker("test", q, time.Second)
+	started := make(chan struct{})
+	var finished int32
+	go worker.Start(HandlerFunc(func(ctx context.Context, msg *M) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		return nil
+	}))
+
+	<-started
+	worker.Stop()
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("expected Stop to wait for the in flight message")
+	}
+}
+
+func TestWorker_VisibilityHeartbeat(t *testing.T) {
+	q := &memoryQ{}
+	_ = q.Message(context.TODO(), &M{Command: "slow"})
+
+	worker := NewWorker("test", q, time.Second)
+	worker.SetVisibilityHeartbeat(20 * time.Millisecond)
+	handled := make(chan struct{})
+	go worker.Start(HandlerFunc(func(ctx context.Context, msg *M) error {
+		time.Sleep(100 * time.Millisecond)
+		close(handled)
+		return nil
+	}))
+
+	<-handled
+	worker.Stop()
+	if atomic.LoadInt32(&q.visibilityN) < 2 {
+		t.Errorf("expected the visibility to be extended while the handler was running, got %d extensions", q.visibilityN)
+	}
+}
+
+func TestWorker_HeartbeatInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		visibility time.Duration
+		want       time.Duration
+	}{
+		{"handler timeout is shorter", time.Minute, 30 * time.Minute, 30 * time.Second},
+		{"visibility is shorter", time.Minute, 20 * time.Second, 10 * time.Second},
+		{"no handler timeout", 0, 20 * time.Second, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worker := NewWorker("test", &memoryQ{}, tt.timeout)
+			worker.SetVisibilityHeartbeat(tt.visibility)
+			if got := worker.heartbeatInterval(); got != tt.want {
+				t.Errorf("heartbeatInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/unanet/go/pkg/errors"
 )
@@ -21,10 +23,14 @@ func NewDownloader(sess *session.Session) *Downloader {
 	}
 }
 
-func (u Downloader) Download(ctx context.Context, location *Location) ([]byte, error) {
+func (u Downloader) Download(ctx context.Context, location *Location) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "s3.download", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("s3.bucket", location.Bucket), attribute.String("s3.key", location.Key)))
+	defer func() { endSpan(span, err) }()
+
 	buf := &aws.WriteAtBuffer{}
 
-	_, err := u.s3.DownloadWithContext(ctx, buf, &s3.GetObjectInput{
+	_, err = u.s3.DownloadWithContext(ctx, buf, &s3.GetObjectInput{
 		Bucket: aws.String(location.Bucket),
 		Key:    aws.String(location.Key),
 	})
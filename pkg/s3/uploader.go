@@ -7,19 +7,19 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/pkg/storage"
 )
 
 type Config struct {
 	Bucket string
 }
 
-type Location struct {
-	Bucket string `json:"bucket"`
-	Key    string `json:"key"`
-	Url    string `json:"url"`
-}
+type Location = storage.Location
 
 type Uploader struct {
 	Bucket string
@@ -33,7 +33,11 @@ func NewUploader(sess *session.Session, config Config) *Uploader {
 	}
 }
 
-func (u Uploader) Upload(ctx context.Context, key string, body []byte) (*Location, error) {
+func (u Uploader) Upload(ctx context.Context, key string, body []byte) (_ *Location, err error) {
+	ctx, span := tracer.Start(ctx, "s3.upload", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("s3.bucket", u.Bucket), attribute.String("s3.key", key)))
+	defer func() { endSpan(span, err) }()
+
 	bodyReader := bytes.NewReader(body)
 	result, err := u.s3.UploadWithContext(ctx, &s3manager.UploadInput{
 		Body:   bodyReader,
@@ -44,6 +48,7 @@ func (u Uploader) Upload(ctx context.Context, key string, body []byte) (*Locatio
 		return nil, errors.Wrap(err)
 	}
 	return &Location{
+		Type:   storage.TypeS3,
 		Bucket: u.Bucket,
 		Key:    key,
 		Url:    result.Location,
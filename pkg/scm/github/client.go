@@ -12,6 +12,7 @@ import (
 	"github.com/unanet/go/pkg/errors"
 	"github.com/unanet/go/pkg/http"
 	"github.com/unanet/go/pkg/log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 )
 
@@ -54,7 +55,7 @@ type (
 func NewClient(cfg Config) *Client {
 	return &Client{
 		cli: &gohttp.Client{
-			Transport: http.LoggingTransport,
+			Transport: otelhttp.NewTransport(http.LoggingTransport),
 			Timeout:   cfg.GithubTimeout,
 		},
 		cfg: cfg,
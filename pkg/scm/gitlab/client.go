@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/dghubble/sling"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	ehttp "github.com/unanet/go/pkg/http"
 	"github.com/unanet/go/pkg/json"
@@ -31,7 +32,7 @@ type Client struct {
 func NewClient(config Config) *Client {
 	var httpClient = &http.Client{
 		Timeout:   config.GitlabTimeout,
-		Transport: ehttp.LoggingTransport,
+		Transport: otelhttp.NewTransport(ehttp.LoggingTransport),
 	}
 
 	if !strings.HasSuffix(config.GitlabBaseUrl, "/") {
@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// FileProvider reads secrets from files under a directory, e.g. for mounted kubernetes secrets. file://db/creds.json#password
+// is the password field of the json object in <dir>/db/creds.json, and without a fragment the whole file is the secret
+type FileProvider struct {
+	dir string
+}
+
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{
+		dir: dir,
+	}
+}
+
+func (p *FileProvider) Secret(ctx context.Context, ref *url.URL) (interface{}, error) {
+	name := filepath.Join(p.dir, filepath.FromSlash(filepath.Clean("/"+ref.Host+ref.Path)))
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ref.Fragment) == 0 {
+		return strings.TrimSpace(string(b)), nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("secret file is not a json object: %w", err)
+	}
+
+	secret, ok := values[ref.Fragment]
+	if !ok {
+		return nil, fmt.Errorf("secret file has no key: %s", ref.Fragment)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/unanet/go/pkg/errors"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+const (
+	SchemeFile  = "file"
+	SchemeVault = "vault"
+)
+
+type Config struct {
+	SecretsFileDir string        `envconfig:"SECRETS_FILE_DIR"`
+	VaultAddr      string        `envconfig:"VAULT_ADDR"`
+	VaultToken     string        `envconfig:"VAULT_TOKEN"`
+	VaultTimeout   time.Duration `envconfig:"VAULT_TIMEOUT" default:"5s"`
+}
+
+// Provider looks up the secret a reference points to, the reference scheme picks the provider
+type Provider interface {
+	Secret(ctx context.Context, ref *url.URL) (interface{}, error)
+}
+
+// Resolver replaces the secret references in metadata with the secrets they point to
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// New registers a provider for every backend that's been configured
+func New(cfg Config) *Resolver {
+	r := &Resolver{
+		providers: make(map[string]Provider),
+	}
+	if len(cfg.SecretsFileDir) > 0 {
+		r.Register(SchemeFile, NewFileProvider(cfg.SecretsFileDir))
+	}
+	if len(cfg.VaultAddr) > 0 {
+		r.Register(SchemeVault, NewVaultProvider(cfg.VaultAddr, cfg.VaultToken, cfg.VaultTimeout))
+	}
+	return r
+}
+
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.providers[scheme] = provider
+}
+
+// Resolve returns a copy of the metadata with every secret reference replaced by its secret, along with the JSON
+// pointers of the values that were replaced so they can be masked again
+func (r *Resolver) Resolve(ctx context.Context, metadata eve.MetadataField) (eve.MetadataField, []string, error) {
+	if metadata == nil {
+		return nil, nil, nil
+	}
+
+	var pointers []string
+	resolved, err := r.resolve(ctx, map[string]interface{}(metadata), "", &pointers)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(pointers)
+
+	return resolved.(map[string]interface{}), pointers, nil
+}
+
+func (r *Resolver) resolve(ctx context.Context, v interface{}, pointer string, pointers *[]string) (interface{}, error) {
+	if ref, ok := eve.SecretReference(v); ok {
+		secret, err := r.secret(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		*pointers = append(*pointers, pointer)
+		return secret, nil
+	}
+
+	switch t := v.(type) {
+	case eve.MetadataField:
+		return r.resolve(ctx, map[string]interface{}(t), pointer, pointers)
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(t))
+		for k, x := range t {
+			value, err := r.resolve(ctx, x, eve.SecretPointer(pointer, k), pointers)
+			if err != nil {
+				return nil, err
+			}
+			resolved[k] = value
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(t))
+		for i, x := range t {
+			value, err := r.resolve(ctx, x, eve.SecretPointer(pointer, strconv.Itoa(i)), pointers)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = value
+		}
+		return resolved, nil
+	default:
+		return v, nil
+	}
+}
+
+func (r *Resolver) secret(ctx context.Context, ref string) (interface{}, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, errors.Wrapf("invalid secret reference: %s", ref)
+	}
+
+	provider, ok := r.providers[u.Scheme]
+	if !ok {
+		return nil, errors.Wrapf("no secret provider configured for: %s", ref)
+	}
+
+	secret, err := provider.Secret(ctx, u)
+	if err != nil {
+		return nil, errors.Wrapf("failed to resolve secret: %s, %s", ref, err)
+	}
+
+	return secret, nil
+}
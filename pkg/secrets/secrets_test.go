@@ -0,0 +1,88 @@
+package secrets
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/unanet/eve/pkg/eve"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "db"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "db", "creds.json"), []byte(`{"password": "hunter2"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "token"), []byte("abc123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(Config{SecretsFileDir: dir})
+	metadata := eve.MetadataField{
+		"db": map[string]interface{}{
+			"host":     "localhost",
+			"password": map[string]interface{}{eve.SecretReferenceKey: "file://db/creds.json#password"},
+		},
+		"tokens":   []interface{}{map[string]interface{}{eve.SecretReferenceKey: "file://token"}},
+		"a/b":      map[string]interface{}{eve.SecretReferenceKey: "file://token"},
+		"loglevel": "info",
+	}
+
+	resolved, pointers, err := r.Resolve(context.Background(), metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := resolved["db"].(map[string]interface{})["password"]; got != "hunter2" {
+		t.Errorf("db.password = %v", got)
+	}
+	if got := resolved["tokens"].([]interface{})[0]; got != "abc123" {
+		t.Errorf("tokens[0] = %v", got)
+	}
+	if want := []string{"/a~1b", "/db/password", "/tokens/0"}; !reflect.DeepEqual(pointers, want) {
+		t.Errorf("pointers = %v, want %v", pointers, want)
+	}
+	if _, ok := eve.SecretReference(metadata["db"].(map[string]interface{})["password"]); !ok {
+		t.Error("the original metadata was modified")
+	}
+
+	masked := eve.MaskSecrets(resolved, pointers)
+	if got := masked["db"].(map[string]interface{})["password"]; got != eve.SecretMask {
+		t.Errorf("masked db.password = %v", got)
+	}
+	if got := masked["a/b"]; got != eve.SecretMask {
+		t.Errorf("masked a/b = %v", got)
+	}
+	if got := resolved["db"].(map[string]interface{})["password"]; got != "hunter2" {
+		t.Error("masking modified the resolved metadata")
+	}
+}
+
+func TestResolver_Resolve_Errors(t *testing.T) {
+	r := New(Config{SecretsFileDir: os.TempDir()})
+
+	_, _, err := r.Resolve(context.Background(), eve.MetadataField{
+		"x": map[string]interface{}{eve.SecretReferenceKey: "vault://secret/app#key"},
+	})
+	if err == nil {
+		t.Error("expected an error for an unconfigured provider")
+	}
+
+	_, _, err = r.Resolve(context.Background(), eve.MetadataField{
+		"x": map[string]interface{}{eve.SecretReferenceKey: "file://../../etc/does-not-exist-eve"},
+	})
+	if err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// VaultProvider reads secrets from a Vault KV version 2 engine. vault://secret/app/db#password is the password key of
+// the app/db secret in the engine mounted at secret
+type VaultProvider struct {
+	addr   string
+	token  string
+	client *http.Client
+}
+
+func NewVaultProvider(addr, token string, timeout time.Duration) *VaultProvider {
+	return &VaultProvider{
+		addr:  strings.TrimSuffix(addr, "/"),
+		token: token,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+type vaultResponse struct {
+	Data struct {
+		Data map[string]interface{} `json:"data"`
+	} `json:"data"`
+}
+
+func (p *VaultProvider) Secret(ctx context.Context, ref *url.URL) (interface{}, error) {
+	if len(ref.Host) == 0 || len(ref.Path) == 0 || len(ref.Fragment) == 0 {
+		return nil, fmt.Errorf("vault references need a mount, path and key")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s/data%s", p.addr, ref.Host, ref.Path), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned: %d", resp.StatusCode)
+	}
+
+	var vr vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&vr); err != nil {
+		return nil, err
+	}
+
+	secret, ok := vr.Data.Data[ref.Fragment]
+	if !ok {
+		return nil, fmt.Errorf("vault secret has no key: %s", ref.Fragment)
+	}
+
+	return secret, nil
+}
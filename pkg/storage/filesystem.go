@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/unanet/go/pkg/errors"
+)
+
+type FilesystemConfig struct {
+	Dir string
+}
+
+// Filesystem stores plans as files in a directory, which is meant for local dev and clusters where
+// eve and the scheduler share a volume
+type Filesystem struct {
+	Dir string
+}
+
+func NewFilesystem(config FilesystemConfig) *Filesystem {
+	return &Filesystem{
+		Dir: config.Dir,
+	}
+}
+
+// path resolves the key under the configured Dir, keys that are absolute or step out with .. are rejected
+func (f Filesystem) path(key string) (string, error) {
+	if len(key) == 0 || filepath.IsAbs(key) {
+		return "", errors.Wrapf("invalid storage key: %s", key)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(key), "/") {
+		if part == ".." {
+			return "", errors.Wrapf("invalid storage key: %s", key)
+		}
+	}
+
+	path := filepath.Join(f.Dir, key)
+	if !strings.HasPrefix(path, filepath.Clean(f.Dir)+string(filepath.Separator)) {
+		return "", errors.Wrapf("invalid storage key: %s", key)
+	}
+	return path, nil
+}
+
+func (f Filesystem) Upload(ctx context.Context, key string, body []byte) (*Location, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	err = ioutil.WriteFile(path, body, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	return &Location{
+		Type:   TypeFilesystem,
+		Bucket: f.Dir,
+		Key:    key,
+		Url:    "file://" + path,
+	}, nil
+}
+
+// Download reads the plan from the configured Dir, the bucket in the location comes off the queue and isn't trusted
+func (f Filesystem) Download(ctx context.Context, location *Location) ([]byte, error) {
+	path, err := f.path(location.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesystem_RoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eve-plans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := NewFilesystem(FilesystemConfig{Dir: dir})
+	location, err := fs.Upload(context.TODO(), "abc.json", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the location goes through the queue as json and is picked back up by the storage type
+	b, err := json.Marshal(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Location
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := Downloaders{TypeFilesystem: fs}.Download(context.TODO(), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "{}" {
+		t.Errorf("expected {}, got %s", body)
+	}
+}
+
+func TestFilesystem_KeyOutsideDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eve-plans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := NewFilesystem(FilesystemConfig{Dir: dir})
+	for _, key := range []string{"../../abc.json", "plans/../../abc.json", "/etc/passwd", ""} {
+		if _, err := fs.Upload(context.TODO(), key, []byte("{}")); err == nil {
+			t.Errorf("expected the upload of key: %q to be rejected", key)
+		}
+	}
+}
+
+func TestFilesystem_DownloadIgnoresBucket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eve-plans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	other, err := ioutil.TempDir("", "eve-other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(other)
+	if err = ioutil.WriteFile(filepath.Join(other, "secret.json"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewFilesystem(FilesystemConfig{Dir: dir})
+	if _, err = fs.Download(context.TODO(), &Location{Type: TypeFilesystem, Bucket: other, Key: "secret.json"}); err == nil {
+		t.Errorf("expected the download to only read from %s", dir)
+	}
+	if _, err = fs.Download(context.TODO(), &Location{Type: TypeFilesystem, Bucket: dir, Key: "../" + filepath.Base(other) + "/secret.json"}); err == nil {
+		t.Errorf("expected a key escaping %s to be rejected", dir)
+	}
+}
+
+func TestLocation_StorageType(t *testing.T) {
+	var location Location
+	if err := json.Unmarshal([]byte(`{"bucket":"eve","key":"abc.json","url":"https://eve.s3.amazonaws.com/abc.json"}`), &location); err != nil {
+		t.Fatal(err)
+	}
+	if location.StorageType() != TypeS3 {
+		t.Errorf("expected a location without a type to be %s, got %s", TypeS3, location.StorageType())
+	}
+}
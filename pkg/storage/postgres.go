@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/unanet/go/pkg/errors"
+)
+
+const postgresTable = "plan_storage"
+
+// Postgres stores plans in the plan_storage table of the eve database
+type Postgres struct {
+	db *sqlx.DB
+}
+
+func NewPostgres(db *sqlx.DB) *Postgres {
+	return &Postgres{
+		db: db,
+	}
+}
+
+func (p Postgres) Upload(ctx context.Context, key string, body []byte) (*Location, error) {
+	now := time.Now().UTC()
+	_, err := p.db.ExecContext(ctx, `
+		insert into plan_storage(key, body, created_at, updated_at)
+		values ($1, $2, $3, $3)
+		on conflict (key) do update set body = excluded.body, updated_at = excluded.updated_at
+	`, key, body, now)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	return &Location{
+		Type:   TypePostgres,
+		Bucket: postgresTable,
+		Key:    key,
+	}, nil
+}
+
+func (p Postgres) Download(ctx context.Context, location *Location) ([]byte, error) {
+	var body []byte
+	err := p.db.QueryRowxContext(ctx, "select body from plan_storage where key = $1", location.Key).Scan(&body)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf("plan with key: %s, not found", location.Key)
+		}
+		return nil, errors.Wrap(err)
+	}
+
+	return body, nil
+}
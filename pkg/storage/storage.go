@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/unanet/go/pkg/errors"
+)
+
+const (
+	TypeS3         = "s3"
+	TypeFilesystem = "filesystem"
+	TypePostgres   = "postgres"
+)
+
+// Location is the envelope that's passed around on the queue in place of the plan itself.
+// Messages written before Type existed don't have one and are S3 locations
+type Location struct {
+	Type   string `json:"type,omitempty"`
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Url    string `json:"url"`
+}
+
+func (l Location) StorageType() string {
+	if len(l.Type) == 0 {
+		return TypeS3
+	}
+	return l.Type
+}
+
+type Uploader interface {
+	Upload(ctx context.Context, key string, body []byte) (*Location, error)
+}
+
+type Downloader interface {
+	Download(ctx context.Context, location *Location) ([]byte, error)
+}
+
+// Downloaders picks the Downloader using the storage type in the location, so messages that were
+// written before the backend was changed can still be read
+type Downloaders map[string]Downloader
+
+func (d Downloaders) Download(ctx context.Context, location *Location) ([]byte, error) {
+	downloader, ok := d[location.StorageType()]
+	if !ok {
+		return nil, errors.Wrapf("no downloader configured for storage type: %s", location.StorageType())
+	}
+	return downloader.Download(ctx, location)
+}